@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is an output file that is written to a temporary file in the
+// same directory and renamed over the target by Commit, so readers never see
+// a partial file and a crash leaves the previous file, if any, intact
+type atomicFile struct {
+	*os.File
+	target    string
+	committed bool
+}
+
+// createAtomicFile creates the temporary file for filename, along with its
+// directory
+func createAtomicFile(filename string) (*atomicFile, error) {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// CreateTemp makes the file private; results files are readable like
+	// those of os.Create under the usual umask
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &atomicFile{File: file, target: filename}, nil
+}
+
+// Commit flushes the file to disk and renames it over the target
+func (f *atomicFile) Commit() error {
+	if err := f.File.Sync(); err != nil {
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.File.Name(), f.target); err != nil {
+		return err
+	}
+	f.committed = true
+	return nil
+}
+
+// Close discards the file unless it was committed
+func (f *atomicFile) Close() error {
+	if f.committed {
+		return nil
+	}
+	f.File.Close()
+	return os.Remove(f.File.Name())
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"subtake/internal/types"
+)
+
+// baselineFilter drops the subdomains whose result in a previous results
+// file is more recent than the cutoff, so only stale and new subdomains are
+// scanned again
+type baselineFilter struct {
+	results map[string]types.Result // by lowercased subdomain
+	cutoff  time.Time
+
+	// fresh lists the baseline results of the dropped subdomains, in input
+	// order; it is complete once the output channel is closed
+	fresh []types.Result
+}
+
+func newBaselineFilter(results []types.Result, cutoff time.Time) *baselineFilter {
+	f := &baselineFilter{results: make(map[string]types.Result, len(results)), cutoff: cutoff}
+	for _, result := range results {
+		key := strings.ToLower(result.Subdomain)
+		if previous, ok := f.results[key]; !ok || result.ScanTime.After(previous.ScanTime) {
+			f.results[key] = result
+		}
+	}
+	return f
+}
+
+// run forwards the subdomains of in that are not in the baseline or whose
+// result there is older than the cutoff
+func (f *baselineFilter) run(in <-chan string, out chan<- string) {
+	defer close(out)
+
+	seen := make(map[string]bool)
+	for subdomain := range in {
+		key := strings.ToLower(subdomain)
+		result, ok := f.results[key]
+		if ok && result.ScanTime.After(f.cutoff) {
+			if !seen[key] {
+				seen[key] = true
+				f.fresh = append(f.fresh, result)
+			}
+			continue
+		}
+		out <- subdomain
+	}
+}
+
+// baselineSubdomains sends the subdomain of every baseline result, for scans
+// whose only input is the baseline
+func baselineSubdomains(results []types.Result, subdomains chan<- string) {
+	for _, result := range results {
+		subdomains <- result.Subdomain
+	}
+}
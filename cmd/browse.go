@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"subtake/internal/output"
+	"subtake/internal/types"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// browseCmd represents the browse command
+var browseCmd = &cobra.Command{
+	Use:   "browse <results.json>",
+	Short: "Interactively browse the findings of a results file",
+	Long: `Browse loads a results file and opens a full-screen browser of its
+vulnerable subdomains: the list of findings on the left, the evidence and
+response details of the selected one on the right.
+
+  up/down, j/k      select a finding
+  pgup/pgdown       move a page through the list
+  tab               switch between the list and the detail pane, which then
+                    scrolls with the same keys
+  /                 filter the findings by service as you type; enter keeps
+                    the filter, esc clears it
+  q, ctrl+c         quit`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBrowse,
+}
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+}
+
+func runBrowse(cmd *cobra.Command, args []string) error {
+	results, err := loadScanResults(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load scan results: %w", err)
+	}
+
+	var findings []types.Result
+	for _, result := range results {
+		if result.Vulnerable && result.Status == "vulnerable" {
+			findings = append(findings, result)
+		}
+	}
+
+	if len(findings) == 0 {
+		fmt.Println("No vulnerable subdomains found in the input file.")
+		return nil
+	}
+
+	if _, err := tea.NewProgram(newBrowser(findings), tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("failed to run browser: %w", err)
+	}
+	return nil
+}
+
+// Styles of the browser
+var (
+	browseTitle    = lipgloss.NewStyle().Bold(true)
+	browseSelected = lipgloss.NewStyle().Reverse(true)
+	browseDim      = lipgloss.NewStyle().Faint(true)
+	browsePane     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder())
+	browseFocused  = browsePane.Copy().BorderForeground(lipgloss.Color("2"))
+)
+
+// browser is the model of the browse TUI
+type browser struct {
+	findings []types.Result
+	visible  []types.Result // findings left by the filter
+	cursor   int            // selected finding in visible
+	top      int            // first finding shown in the list pane
+
+	filter    textinput.Model
+	filtering bool // keys go to the filter input
+	inDetail  bool // keys scroll the detail pane
+
+	detail        viewport.Model
+	width, height int
+}
+
+func newBrowser(findings []types.Result) *browser {
+	filter := textinput.New()
+	filter.Prompt = "service: "
+	filter.Placeholder = "filter by service"
+
+	return &browser{findings: findings, visible: findings, filter: filter}
+}
+
+func (b *browser) Init() tea.Cmd {
+	return nil
+}
+
+func (b *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		b.width, b.height = msg.Width, msg.Height
+		b.resize()
+		return b, nil
+
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC {
+			return b, tea.Quit
+		}
+		if b.filtering {
+			return b.updateFilter(msg)
+		}
+
+		switch msg.String() {
+		case "q":
+			return b, tea.Quit
+		case "tab":
+			b.inDetail = !b.inDetail
+			return b, nil
+		case "/":
+			b.filtering = true
+			b.inDetail = false
+			return b, b.filter.Focus()
+		case "esc":
+			b.inDetail = false
+			return b, nil
+		}
+
+		if b.inDetail {
+			var cmd tea.Cmd
+			b.detail, cmd = b.detail.Update(msg)
+			return b, cmd
+		}
+		switch msg.String() {
+		case "up", "k":
+			b.selectFinding(b.cursor - 1)
+		case "down", "j":
+			b.selectFinding(b.cursor + 1)
+		case "pgup":
+			b.selectFinding(b.cursor - b.listHeight())
+		case "pgdown":
+			b.selectFinding(b.cursor + b.listHeight())
+		case "home", "g":
+			b.selectFinding(0)
+		case "end", "G":
+			b.selectFinding(len(b.visible) - 1)
+		}
+		return b, nil
+	}
+	return b, nil
+}
+
+// updateFilter handles the keys typed into the filter, which applies as it
+// changes
+func (b *browser) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		b.filtering = false
+		b.filter.Blur()
+		return b, nil
+	case tea.KeyEsc:
+		b.filtering = false
+		b.filter.Blur()
+		b.filter.SetValue("")
+		b.applyFilter()
+		return b, nil
+	}
+
+	var cmd tea.Cmd
+	b.filter, cmd = b.filter.Update(msg)
+	b.applyFilter()
+	return b, cmd
+}
+
+// applyFilter narrows the list to the findings of the filtered service
+func (b *browser) applyFilter() {
+	b.visible = filterFindings(b.findings, strings.TrimSpace(b.filter.Value()))
+	b.top = 0
+	b.selectFinding(0)
+}
+
+// selectFinding moves the cursor to the finding at index, within bounds,
+// scrolls the list to it and shows its details
+func (b *browser) selectFinding(index int) {
+	b.cursor = max(0, min(index, len(b.visible)-1))
+
+	height := b.listHeight()
+	if b.cursor < b.top {
+		b.top = b.cursor
+	} else if height > 0 && b.cursor >= b.top+height {
+		b.top = b.cursor - height + 1
+	}
+
+	var details strings.Builder
+	if len(b.visible) > 0 {
+		output.WriteDetailed(&details, b.visible[b.cursor])
+	}
+	// Long lines such as bodies and snippets wrap within the pane
+	content := strings.TrimLeft(details.String(), "\n")
+	b.detail.SetContent(lipgloss.NewStyle().Width(b.detail.Width).Render(content))
+	b.detail.GotoTop()
+}
+
+// resize lays the panes out for the terminal size
+func (b *browser) resize() {
+	b.detail = viewport.New(b.detailWidth()-2, b.paneHeight()-2)
+	b.selectFinding(b.cursor)
+}
+
+// paneHeight is the height of both panes, borders included: the terminal
+// less the title and help lines
+func (b *browser) paneHeight() int {
+	return max(3, b.height-2)
+}
+
+// listHeight is the number of findings the list pane shows at once
+func (b *browser) listHeight() int {
+	return b.paneHeight() - 2
+}
+
+// listWidth is the width of the list pane, borders included
+func (b *browser) listWidth() int {
+	return max(20, b.width*2/5)
+}
+
+// detailWidth is the width of the detail pane, borders included
+func (b *browser) detailWidth() int {
+	return max(20, b.width-b.listWidth())
+}
+
+func (b *browser) View() string {
+	if b.width == 0 {
+		return ""
+	}
+
+	title := browseTitle.Render(fmt.Sprintf("%d of %d findings", len(b.visible), len(b.findings)))
+	if value := strings.TrimSpace(b.filter.Value()); value != "" && !b.filtering {
+		title += browseDim.Render(fmt.Sprintf("  service %q", value))
+	}
+	if b.filtering {
+		title += "  " + b.filter.View()
+	}
+
+	listPane, detailPane := browseFocused, browsePane
+	if b.inDetail {
+		listPane, detailPane = browsePane, browseFocused
+	}
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		listPane.Width(b.listWidth()-2).Height(b.paneHeight()-2).Render(b.listView()),
+		detailPane.Width(b.detailWidth()-2).Height(b.paneHeight()-2).Render(b.detail.View()),
+	)
+
+	help := "↑/↓ select · tab switch pane · / filter by service · q quit"
+	if b.filtering {
+		help = "enter keep filter · esc clear filter"
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, title, panes, browseDim.Render(help))
+}
+
+// listView renders the findings shown in the list pane, one per line
+func (b *browser) listView() string {
+	if len(b.visible) == 0 {
+		return browseDim.Render("no findings of this service")
+	}
+
+	width := b.listWidth() - 2
+	line := lipgloss.NewStyle().MaxWidth(width)
+	end := min(len(b.visible), b.top+b.listHeight())
+	rows := make([]string, 0, end-b.top)
+	for i := b.top; i < end; i++ {
+		finding := b.visible[i]
+		row := fmt.Sprintf("%s  %s (%d)", finding.Subdomain, finding.PrimaryService(), finding.Score)
+		if i == b.cursor {
+			row = browseSelected.Width(width).Render(row)
+		}
+		rows = append(rows, line.Render(row))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// filterFindings returns the findings with evidence from a service containing
+// the filter text
+func filterFindings(findings []types.Result, filter string) []types.Result {
+	if filter == "" {
+		return findings
+	}
+
+	filter = strings.ToLower(filter)
+	var filtered []types.Result
+	for _, finding := range findings {
+		for _, evidence := range finding.Evidence {
+			if strings.Contains(strings.ToLower(evidence.Service), filter) {
+				filtered = append(filtered, finding)
+				break
+			}
+		}
+	}
+	return filtered
+}
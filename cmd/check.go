@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"subtake/internal/dns"
+	"subtake/internal/fingerprints"
+	"subtake/internal/output"
+	"subtake/internal/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkJSON bool
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check <subdomain>",
+	Short: "Scan a single subdomain and print a detailed report",
+	Long: `Check scans a single subdomain and prints the full detailed report:
+status, all evidence with snippets, both HTTP and HTTPS responses, the
+CNAME chain and TLS information. Nothing is written to disk.
+
+Use --json to print the raw result instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().BoolVar(&checkJSON, "json", false, "print the raw result as JSON")
+	addProbeFlags(checkCmd)
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	if !checkJSON {
+		showBanner()
+	}
+
+	cfg := buildConfig()
+
+	// Load fingerprints
+	fp, err := fingerprints.Load(fingerprintsFile, importFormat)
+	if err != nil {
+		return fmt.Errorf("failed to load fingerprints: %w", err)
+	}
+
+	s, err := scanner.New(cfg, fp)
+	if err != nil {
+		return fmt.Errorf("failed to create scanner: %w", err)
+	}
+	defer s.Cleanup()
+
+	subdomain, err := dns.ToASCII(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid internationalized subdomain %q: %w", args[0], err)
+	}
+	result := s.Scan([]string{subdomain})[0]
+
+	if checkJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	output.PrintDetailed(result)
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"subtake/internal/scanner"
+	"subtake/internal/types"
+)
+
+// checkpoint records the progress of a chunked scan, so an interrupted scan
+// can be resumed with --resume after the last completed chunk
+type checkpoint struct {
+	List          string    `json:"list"`
+	ChunkSize     int       `json:"chunk_size"`
+	Processed     int       `json:"processed"`      // subdomains of the input scanned
+	Written       int       `json:"written"`        // results in the output file
+	Offset        int64     `json:"offset"`         // size of the output file
+	Seed          int64     `json:"seed,omitempty"` // seed of the --sample, if any
+	ScanStartedAt time.Time `json:"scan_started_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// checkpointFile returns the checkpoint file of an output file
+func checkpointFile(outputFile string) string {
+	return outputFile + ".checkpoint"
+}
+
+func loadCheckpoint(filename string) (*checkpoint, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// save writes the checkpoint to a temporary file first, so an interruption
+// never leaves a truncated checkpoint behind
+func (cp *checkpoint) save(filename string) error {
+	cp.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// openResumedOutput reopens the output file of an interrupted scan, dropping
+// whatever was written after the last checkpoint
+func openResumedOutput(filename string, offset int64) (*os.File, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(offset, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// scanChunks scans the subdomains of in in chunks of size, calling done
+// after each completed chunk with the number of subdomains processed so far.
+// The first skip subdomains were processed by an earlier run and are passed
+// over. A chunk interrupted by cancelling the context is not reported.
+func scanChunks(ctx context.Context, s *scanner.Scanner, in <-chan string, size, skip int, emit func(types.Result), done func(processed int) error) error {
+	// Drain the rest of the input on return so the readers are not blocked
+	defer func() {
+		for range in {
+		}
+	}()
+
+	processed := 0
+	for ; processed < skip; processed++ {
+		if _, ok := <-in; !ok {
+			return nil
+		}
+	}
+	if skip > 0 {
+		slog.Info("resuming scan", "skipped", skip)
+	}
+
+	for ctx.Err() == nil {
+		chunk := make(chan string, size)
+		n := 0
+		for ; n < size; n++ {
+			subdomain, ok := <-in
+			if !ok {
+				break
+			}
+			chunk <- subdomain
+		}
+		close(chunk)
+		if n == 0 {
+			return nil
+		}
+
+		s.ScanStream(ctx, chunk, emit)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		processed += n
+		if err := done(processed); err != nil {
+			return err
+		}
+		slog.Info("chunk scanned", "subdomains", n, "processed", processed)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"subtake/internal/dns"
+)
+
+// ptrWorkers is the number of concurrent PTR lookups of a CIDR input
+const ptrWorkers = 20
+
+// readCIDRs expands every CIDR block or IP address read from r (one per line,
+// blank lines and # comments ignored) and sends the host names found by
+// reverse DNS lookups of its addresses to the channel, each once. Addresses
+// without PTR records are skipped. Trailing "#key=value" tags of a line are
+// recorded for its host names. More than maxAddresses addresses in total is
+// an error, a guard against expanding a huge block by mistake.
+func readCIDRs(r io.Reader, maxAddresses int, tags *inputTags, subdomains chan<- string) error {
+	lines := bufio.NewScanner(r)
+	number := 0
+	total := 0
+	lookups := ptrLookups{seen: make(map[string]bool)}
+	for lines.Scan() {
+		number++
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, lineTags := splitTags(line)
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", number, err)
+		}
+
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits >= 31 || total+1<<hostBits > maxAddresses {
+			return fmt.Errorf("line %d: CIDR input expands to more than %s addresses; raise --max-cidr-addresses or split the block",
+				number, groupDigits(int64(maxAddresses)))
+		}
+		total += 1 << hostBits
+
+		lookups.expand(prefix, func(host string) {
+			if lineTags != nil {
+				tags.set(host, lineTags)
+			}
+			subdomains <- host
+		})
+	}
+
+	slog.Info("expanded CIDR input", "addresses", lookups.addresses, "without_ptr", lookups.missing,
+		"failed", lookups.failed, "hostnames", len(lookups.seen))
+	return lines.Err()
+}
+
+// parsePrefix parses a CIDR block, or a single address as a block of one
+func parsePrefix(entry string) (netip.Prefix, error) {
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("expected a CIDR block or IP address, got %q", entry)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("expected a CIDR block or IP address, got %q", entry)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ptrLookups resolves the addresses of CIDR blocks to host names, counting
+// the outcomes and dropping names seen before
+type ptrLookups struct {
+	addresses, missing, failed int
+	seen                       map[string]bool
+}
+
+// expand looks up the PTR records of every address of the block concurrently
+// and calls found with each new host name
+func (l *ptrLookups) expand(prefix netip.Prefix, found func(host string)) {
+	addrs := make(chan netip.Addr)
+	go func() {
+		defer close(addrs)
+		for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			addrs <- addr
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < ptrWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range addrs {
+				names, err := dns.LookupPTR(context.Background(), addr)
+				if err != nil {
+					slog.Debug("PTR lookup failed", "ip", addr.String(), "error", err)
+				}
+
+				mu.Lock()
+				l.addresses++
+				switch {
+				case err != nil:
+					l.failed++
+				case len(names) == 0:
+					l.missing++
+				}
+				for _, name := range names {
+					if !l.seen[name] {
+						l.seen[name] = true
+						found(name)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		entry   string
+		want    string
+		wantErr bool
+	}{
+		{entry: "192.0.2.0/24", want: "192.0.2.0/24"},
+		{entry: "192.0.2.77/24", want: "192.0.2.0/24"},
+		{entry: "192.0.2.10", want: "192.0.2.10/32"},
+		{entry: "2001:db8::/126", want: "2001:db8::/126"},
+		{entry: "2001:db8::1", want: "2001:db8::1/128"},
+		{entry: "192.0.2.0/33", wantErr: true},
+		{entry: "example.com", wantErr: true},
+		{entry: "192.0.2/24", wantErr: true},
+		{entry: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePrefix(tt.entry)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePrefix(%q) = %s, want an error", tt.entry, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePrefix(%q) failed: %v", tt.entry, err)
+			continue
+		}
+		if got != netip.MustParsePrefix(tt.want) {
+			t.Errorf("parsePrefix(%q) = %s, want %s", tt.entry, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+
+	"subtake/internal/output"
+	"subtake/internal/scanner"
+)
+
+// downgradedCount returns the number of findings downgraded by
+// --trim-cdn-wildcards
+func downgradedCount(clusters []scanner.Cluster) int {
+	count := 0
+	for _, cluster := range clusters {
+		if cluster.Downgraded {
+			count += cluster.Size
+		}
+	}
+	return count
+}
+
+// printClusters lists the clusters of findings with an identical response
+// body, largest first
+func printClusters(clusters []scanner.Cluster, minSize int) {
+	fmt.Printf("\n--- Identical Response Clusters (%d or more findings) ---\n", minSize)
+	for _, cluster := range clusters {
+		verdict := fmt.Sprintf("%skept%s, strong evidence", output.ColorRed, output.ColorReset)
+		if cluster.Downgraded {
+			verdict = fmt.Sprintf("%sdowngraded%s, generic or weak evidence only", output.ColorYellow, output.ColorReset)
+		}
+		fmt.Printf("  %s: %d findings (%s)\n", cluster.Hash, cluster.Size, verdict)
+	}
+	fmt.Printf("Clustered false positives: %d\n", downgradedCount(clusters))
+}
@@ -1,12 +1,21 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"strconv"
+	"strings"
 
+	"subtake/internal/dns"
+	"subtake/internal/output"
 	"subtake/internal/types"
 
 	"github.com/spf13/cobra"
@@ -15,6 +24,8 @@ import (
 var (
 	digInputFile  string
 	digOutputFile string
+	digFormat     string
+	digCountOnly  bool
 )
 
 // digCmd represents the dig command
@@ -25,8 +36,13 @@ var digCmd = &cobra.Command{
 dig <subdomain> ANY +noall +answer
 
 This command reads from a JSON file containing scan results and runs dig
-on all subdomains that were marked as vulnerable.`,
-	Run: runDig,
+on all subdomains that were marked as vulnerable. With --format json the
+results are printed to stdout as JSON instead of text. The command exits
+non-zero when no dig succeeds.
+
+With --count-only the subdomains are resolved without dig and only a summary
+of how many resolve and how many are NXDOMAIN is printed.`,
+	RunE: runDig,
 }
 
 func init() {
@@ -34,76 +50,231 @@ func init() {
 
 	digCmd.Flags().StringVarP(&digInputFile, "input", "i", "", "Input JSON file with scan results (required)")
 	digCmd.Flags().StringVarP(&digOutputFile, "output", "o", "", "Output file for dig results (default: stdout)")
+	digCmd.Flags().StringVar(&digFormat, "format", "text", "Output format: text or json")
+	digCmd.Flags().BoolVar(&digCountOnly, "count-only", false, "Only print how many vulnerable subdomains resolve and how many are NXDOMAIN")
 	digCmd.MarkFlagRequired("input")
 }
 
-func runDig(cmd *cobra.Command, args []string) {
+func runDig(cmd *cobra.Command, args []string) error {
+	if digFormat != "text" && digFormat != "json" {
+		return fmt.Errorf("invalid --format %q (expected text or json)", digFormat)
+	}
+	jsonOutput := digFormat == "json"
+
 	// Show banner
-	showBanner()
+	if !jsonOutput && !digCountOnly {
+		showBanner()
+	}
+
 	// Load scan results from JSON file
 	results, err := loadScanResults(digInputFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading scan results: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to load scan results: %w", err)
 	}
 
 	// Filter vulnerable subdomains
 	vulnerableSubdomains := filterVulnerableSubdomains(results)
 
+	if digCountOnly {
+		return countResolving(vulnerableSubdomains, jsonOutput)
+	}
+
 	if len(vulnerableSubdomains) == 0 {
+		if jsonOutput {
+			return writeDigJSON([]DigResult{})
+		}
 		fmt.Println("No vulnerable subdomains found in the input file.")
-		return
+		return nil
 	}
 
-	fmt.Printf("Found %d vulnerable subdomains to verify:\n", len(vulnerableSubdomains))
-	for _, subdomain := range vulnerableSubdomains {
-		fmt.Printf("- %s\n", subdomain)
+	if !jsonOutput {
+		fmt.Printf("Found %d vulnerable subdomains to verify:\n", len(vulnerableSubdomains))
+		for _, subdomain := range vulnerableSubdomains {
+			fmt.Printf("- %s\n", subdomain)
+		}
+		fmt.Println()
 	}
-	fmt.Println()
 
 	// Run dig on each vulnerable subdomain
 	digResults := make([]DigResult, 0, len(vulnerableSubdomains))
+	succeeded := 0
 
 	for _, subdomain := range vulnerableSubdomains {
-		fmt.Printf("Running dig on %s...\n", subdomain)
+		if !jsonOutput {
+			fmt.Printf("Running dig on %s...\n", subdomain)
+		}
 		result := runDigCommand(subdomain)
 		digResults = append(digResults, result)
+		if result.Success {
+			succeeded++
+		}
 
 		// Print result immediately
-		printDigResult(result)
+		if !jsonOutput {
+			printDigResult(result)
+		}
 	}
 
-	// Save results to output file if specified
-	if digOutputFile != "" {
-		err := saveDigResults(digResults, digOutputFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error saving results: %v\n", err)
-			os.Exit(1)
+	if jsonOutput {
+		if err := writeDigJSON(digResults); err != nil {
+			return err
+		}
+	} else if digOutputFile != "" {
+		// Save results to output file if specified
+		if err := saveDigResults(digResults, digOutputFile); err != nil {
+			return fmt.Errorf("failed to save results: %w", err)
 		}
 		fmt.Printf("\nResults saved to: %s\n", digOutputFile)
 	}
+
+	if succeeded == 0 {
+		return fmt.Errorf("dig failed for all %d subdomains", len(digResults))
+	}
+	return nil
+}
+
+// DigCounts summarizes the resolution of the vulnerable subdomains
+type DigCounts struct {
+	Subdomains int `json:"subdomains"`
+	Resolve    int `json:"resolve"`
+	NXDOMAIN   int `json:"nxdomain"`
+	Failed     int `json:"failed"`
+}
+
+// countResolving resolves the A and AAAA records of each subdomain with the
+// resolver of the scan and prints how many resolve and how many do not exist.
+// Subdomains with no address that do exist are only part of the total.
+func countResolving(subdomains []string, jsonOutput bool) error {
+	counts := DigCounts{Subdomains: len(subdomains)}
+	for _, subdomain := range subdomains {
+		host := subdomain
+		if h, _, err := net.SplitHostPort(subdomain); err == nil {
+			host = h
+		}
+
+		records, err := dns.Records(context.Background(), host)
+		switch {
+		case errors.Is(err, dns.ErrNXDOMAIN):
+			counts.NXDOMAIN++
+		case err != nil:
+			slog.Warn("DNS lookup failed", "subdomain", subdomain, "error", err)
+			counts.Failed++
+		case hasAddress(records):
+			counts.Resolve++
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(counts); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("%d subdomains, %d resolve, %d NXDOMAIN", counts.Subdomains, counts.Resolve, counts.NXDOMAIN)
+		if counts.Failed > 0 {
+			fmt.Printf(", %d failed", counts.Failed)
+		}
+		fmt.Println()
+	}
+
+	if counts.Subdomains > 0 && counts.Failed == counts.Subdomains {
+		return fmt.Errorf("DNS lookup failed for all %d subdomains", counts.Subdomains)
+	}
+	return nil
+}
+
+// hasAddress reports whether the records include an A or AAAA record
+func hasAddress(records []dns.Record) bool {
+	for _, record := range records {
+		if record.Type == "A" || record.Type == "AAAA" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDigJSON writes the results as JSON to the output file, or to stdout
+// when none is given
+func writeDigJSON(results []DigResult) error {
+	if digOutputFile != "" {
+		if err := saveDigResults(results, digOutputFile); err != nil {
+			return fmt.Errorf("failed to save results: %w", err)
+		}
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
 }
 
 // DigResult represents the result of a dig command
 type DigResult struct {
-	Subdomain string `json:"subdomain"`
-	Command   string `json:"command"`
-	Output    string `json:"output"`
-	Error     string `json:"error,omitempty"`
-	Success   bool   `json:"success"`
+	Subdomain string      `json:"subdomain"`
+	Command   string      `json:"command"`
+	Output    string      `json:"output"`
+	Records   []DigRecord `json:"records,omitempty"`
+	CNAMEs    []string    `json:"cnames,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Success   bool        `json:"success"`
 }
 
+// DigRecord is one answer record parsed from the dig output
+type DigRecord struct {
+	Name  string `json:"name"`
+	TTL   int    `json:"ttl"`
+	Class string `json:"class"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// loadScanResults reads scan results from a results document, a bare JSON
+// array, a single JSON object or a stream of JSON objects (JSONL)
 func loadScanResults(filename string) ([]types.Result, error) {
-	file, err := os.Open(filename)
+	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
 
 	var results []types.Result
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&results)
-	return results, err
+	switch trimmed[0] {
+	case '[':
+		err = json.Unmarshal(trimmed, &results)
+		return results, err
+	case '{':
+		// A results document carries its schema version
+		var document output.Document
+		if json.Unmarshal(trimmed, &document) == nil && document.SchemaVersion > 0 {
+			if document.SchemaVersion > output.SchemaVersion {
+				slog.Warn("results file has a newer schema version, some fields may be ignored", "file", filename,
+					"schema_version", document.SchemaVersion, "supported", output.SchemaVersion)
+			}
+			return document.Results, nil
+		}
+
+		// Decode a stream of objects; a single object is a stream of one
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		for {
+			var result types.Result
+			err := decoder.Decode(&result)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	default:
+		return nil, fmt.Errorf("%s does not contain JSON scan results", filename)
+	}
 }
 
 func filterVulnerableSubdomains(results []types.Result) []string {
@@ -132,11 +303,48 @@ func runDigCommand(subdomain string) DigResult {
 
 	if err != nil {
 		result.Error = err.Error()
+	} else {
+		result.Records = parseDigAnswer(result.Output)
+		for _, record := range result.Records {
+			if record.Type == "CNAME" {
+				result.CNAMEs = append(result.CNAMEs, record.Value)
+			}
+		}
 	}
 
 	return result
 }
 
+// parseDigAnswer parses the records of dig's answer section, one per line as
+// "name ttl class type value"; comments and malformed lines are skipped
+func parseDigAnswer(output string) []DigRecord {
+	var records []DigRecord
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		ttl, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+
+		records = append(records, DigRecord{
+			Name:  strings.TrimSuffix(fields[0], "."),
+			TTL:   ttl,
+			Class: fields[2],
+			Type:  fields[3],
+			Value: strings.TrimSuffix(strings.Join(fields[4:], " "), "."),
+		})
+	}
+	return records
+}
+
 func printDigResult(result DigResult) {
 	fmt.Printf("\n--- Dig Results for %s ---\n", result.Subdomain)
 	fmt.Printf("Command: %s\n", result.Command)
@@ -155,16 +363,7 @@ func printDigResult(result DigResult) {
 }
 
 func saveDigResults(results []DigResult, filename string) error {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(filename)
-	if dir != "" {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return err
-		}
-	}
-
-	file, err := os.Create(filename)
+	file, err := createAtomicFile(filename)
 	if err != nil {
 		return err
 	}
@@ -172,5 +371,8 @@ func saveDigResults(results []DigResult, filename string) error {
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
-	return encoder.Encode(results)
+	if err := encoder.Encode(results); err != nil {
+		return err
+	}
+	return file.Commit()
 }
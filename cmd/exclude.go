@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"subtake/internal/types"
+)
+
+// exclusions is a list of subdomains that are known to be safe, given
+// exactly or as glob patterns such as "*.parked.example.com"
+type exclusions struct {
+	exact    map[string]bool
+	patterns []string
+}
+
+// loadExclusions reads an exclusion file with one subdomain or pattern per
+// line. Blank lines and # comments are ignored.
+func loadExclusions(filename string) (*exclusions, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &exclusions{exact: make(map[string]bool)}
+	lines := bufio.NewScanner(file)
+	number := 0
+	for lines.Scan() {
+		number++
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, _ := splitTags(line)
+		entry = strings.TrimSuffix(strings.ToLower(entry), ".")
+		if !strings.ContainsAny(entry, "*?[") {
+			list.exact[entry] = true
+			continue
+		}
+		if _, err := path.Match(entry, ""); err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern %q: %w", number, entry, err)
+		}
+		list.patterns = append(list.patterns, entry)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// match checks if the subdomain is excluded. A subdomain with a port is also
+// excluded by an entry for its host.
+func (e *exclusions) match(subdomain string) bool {
+	subdomain = strings.TrimSuffix(strings.ToLower(subdomain), ".")
+	candidates := []string{subdomain}
+	if host, _, err := net.SplitHostPort(subdomain); err == nil {
+		candidates = append(candidates, host)
+	}
+
+	for _, candidate := range candidates {
+		if e.exact[candidate] {
+			return true
+		}
+		for _, pattern := range e.patterns {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exclusionFilter drops the excluded subdomains from the input before they
+// are scanned
+type exclusionFilter struct {
+	list *exclusions
+
+	// excluded lists the subdomains that were dropped; it is complete once
+	// the output channel is closed
+	excluded []string
+}
+
+// run forwards the subdomains of in that are not excluded
+func (f *exclusionFilter) run(in <-chan string, out chan<- string) {
+	defer close(out)
+
+	seen := make(map[string]bool)
+	for subdomain := range in {
+		if f.list.match(subdomain) {
+			if key := strings.ToLower(subdomain); !seen[key] {
+				seen[key] = true
+				f.excluded = append(f.excluded, subdomain)
+			}
+			continue
+		}
+		out <- subdomain
+	}
+}
+
+// excludedResult is the result reported for an excluded subdomain with
+// --exclude-mode mark: not vulnerable, without having been probed
+func excludedResult(subdomain string) types.Result {
+	return types.Result{
+		Subdomain: subdomain,
+		Status:    "not vulnerable",
+		Excluded:  true,
+		ScanTime:  time.Now(),
+	}
+}
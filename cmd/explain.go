@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"subtake/internal/dns"
+	"subtake/internal/fingerprints"
+	"subtake/internal/output"
+	"subtake/internal/scanner"
+	"subtake/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain <subdomain>",
+	Short: "Explain why a subdomain was or was not flagged",
+	Long: `Explain scans a single subdomain like check does, then goes through every
+fingerprint and prints whether it was evaluated against the checked response
+and why it did or did not match: status out of range, header mismatch,
+pattern absent or regex without a hit. Use it to debug false positives and
+false negatives.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	addProbeFlags(explainCmd)
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	showBanner()
+
+	cfg := buildConfig()
+
+	fp, err := fingerprints.Load(fingerprintsFile, importFormat)
+	if err != nil {
+		return fmt.Errorf("failed to load fingerprints: %w", err)
+	}
+
+	s, err := scanner.New(cfg, fp)
+	if err != nil {
+		return fmt.Errorf("failed to create scanner: %w", err)
+	}
+	defer s.Cleanup()
+
+	subdomain, err := dns.ToASCII(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid internationalized subdomain %q: %w", args[0], err)
+	}
+	result := s.Scan([]string{subdomain})[0]
+
+	fmt.Printf("Subdomain: %s\n", result.Subdomain)
+	if len(result.CNAME) > 0 {
+		fmt.Printf("CNAME: %v\n", result.CNAME)
+	}
+	fmt.Printf("Verdict: %s (score %d, threshold %d)\n", result.Status, result.Score, cfg.Threshold)
+	if result.LikelyLegit {
+		fmt.Println("Likely legit: only CNAME or service specific evidence counts, other matches weigh 0")
+	}
+
+	resp := result.CheckedResponse()
+	if resp == nil {
+		if result.Error != "" {
+			fmt.Printf("Error: %s\n", result.Error)
+		}
+		fmt.Println("\nNo response was checked, so no fingerprint was evaluated")
+		return nil
+	}
+	fmt.Printf("Checked: %s (status %d, %d bytes)\n", resp.URL, resp.StatusCode, len(resp.Body))
+
+	// The target the scanner matched the fingerprints against
+	body := resp.Body
+	switch {
+	case resp.Binary:
+		body = ""
+		fmt.Println("The body is binary and is not matched against")
+	case resp.Error != "":
+		body = resp.Raw
+	}
+	explanations := fp.Explain(&fingerprints.Target{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		Headers:    http.Header(resp.Headers),
+		CNAME:      result.CNAME,
+		Service:    serviceOf(result),
+	})
+	for i := range explanations {
+		explainRequest(&explanations[i], result)
+	}
+
+	printExplanations("Matched", output.ColorGreen, explanations, func(e fingerprints.Explanation) bool {
+		return e.Match != nil
+	})
+	printExplanations("Not matched", output.ColorRed, explanations, func(e fingerprints.Explanation) bool {
+		return e.Evaluated && e.Match == nil
+	})
+	printExplanations("Not evaluated", output.ColorYellow, explanations, func(e fingerprints.Explanation) bool {
+		return !e.Evaluated
+	})
+
+	// Evidence that no fingerprint explains
+	for _, evidence := range result.Evidence {
+		if evidence.MatchField == fingerprints.FieldExternal {
+			fmt.Printf("\nExternal matcher: %s (weight %d)\n", evidence.Service, evidence.Weight)
+		}
+	}
+	return nil
+}
+
+// explainRequest fills in the outcome of a fingerprint with its own request,
+// which the scanner sent and matched itself
+func explainRequest(explanation *fingerprints.Explanation, result types.Result) {
+	fingerprint := &explanation.Fingerprint
+	if fingerprint.Request == nil || !fingerprint.IsEnabled() {
+		return
+	}
+	if !allowPost {
+		explanation.Reason = fmt.Sprintf("sends its own request (%s), only with --allow-post", fingerprint.Request)
+		return
+	}
+
+	explanation.Evaluated = true
+	index := slices.IndexFunc(result.Evidence, func(evidence types.Evidence) bool {
+		return evidence.FingerprintID == fingerprint.ID
+	})
+	if index < 0 {
+		explanation.Reason = fmt.Sprintf("no match in the response to %s, or not sent as the CNAME does not point at the service", fingerprint.Request)
+		return
+	}
+
+	evidence := result.Evidence[index]
+	explanation.Match = &fingerprints.Match{Fingerprint: *fingerprint, Weight: evidence.Weight, Field: evidence.MatchField}
+	explanation.Reason = fmt.Sprintf("matched the response to %s on %s, weight %d", fingerprint.Request, evidence.MatchField, evidence.Weight)
+}
+
+// printExplanations lists the explanations selected by keep under a heading
+func printExplanations(heading, color string, explanations []fingerprints.Explanation, keep func(fingerprints.Explanation) bool) {
+	var selected []fingerprints.Explanation
+	for _, explanation := range explanations {
+		if keep(explanation) {
+			selected = append(selected, explanation)
+		}
+	}
+	if len(selected) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s%s (%d)%s\n", color, heading, len(selected), output.ColorReset)
+	for _, explanation := range selected {
+		fmt.Printf("  %s (%s): %s\n", explanation.Fingerprint.ID, explanation.Fingerprint.Service, explanation.Reason)
+	}
+}
+
+// serviceOf returns the service the CNAME chain of the result points at
+func serviceOf(result types.Result) string {
+	if result.Service == nil {
+		return ""
+	}
+	return result.Service.Name
+}
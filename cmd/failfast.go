@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"subtake/internal/scanner"
+	"subtake/internal/types"
+)
+
+// failFast aborts a scan that keeps failing for reasons unrelated to the
+// targets, such as an unreachable resolver, instead of reporting every
+// remaining subdomain as an error. Results are recorded one at a time.
+type failFast struct {
+	threshold int
+	stop      func()
+
+	streak int
+	last   string
+	err    error
+}
+
+// record counts a result towards the run of consecutive operational errors.
+// Any other result, including a target error, ends the run.
+func (f *failFast) record(result types.Result) {
+	if f.err != nil {
+		return
+	}
+	if result.Status != "error" || !scanner.OperationalError(result.Error) {
+		f.streak = 0
+		return
+	}
+
+	f.streak++
+	f.last = result.Error
+	if f.streak >= f.threshold {
+		f.err = fmt.Errorf("aborted after %d consecutive operational errors (last: %s): check the network connection and the resolver", f.streak, f.last)
+		f.stop()
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"subtake/internal/fingerprints"
+	"subtake/internal/httpclient"
+	"subtake/internal/scanner"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// fingerprintsCmd groups the fingerprint management commands
+var fingerprintsCmd = &cobra.Command{
+	Use:     "fingerprints",
+	Aliases: []string{"fingerprint"},
+	Short:   "Manage fingerprints",
+}
+
+// fingerprintNewCmd represents the fingerprints new command
+var fingerprintNewCmd = &cobra.Command{
+	Use:   "new <service> <url>",
+	Short: "Generate a starter fingerprint from a sample URL",
+	Long: `New fetches the sample URL and prints a starter fingerprint entry in YAML
+for the service, using a distinctive line of the response body as the
+pattern. Refine the entry and add it to your custom fingerprints file.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runFingerprintNew,
+}
+
+// fingerprintListCmd represents the fingerprints list command
+var fingerprintListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the effective fingerprints",
+	Long: `List loads the default fingerprints merged with the custom file, if any,
+and prints the service, pattern, regex flag, state (enabled or disabled) and
+source of each entry.`,
+	Args: cobra.NoArgs,
+	RunE: runFingerprintList,
+}
+
+var listCount bool
+
+func init() {
+	rootCmd.AddCommand(fingerprintsCmd)
+	fingerprintsCmd.AddCommand(fingerprintNewCmd)
+	fingerprintsCmd.AddCommand(fingerprintListCmd)
+
+	addProbeFlags(fingerprintNewCmd)
+
+	fingerprintListCmd.Flags().StringVar(&fingerprintsFile, "fingerprints", "", "custom fingerprints file (JSON/YAML)")
+	fingerprintListCmd.Flags().StringVar(&importFormat, "import-format", fingerprints.FormatSubtake, "format of the custom fingerprints file: subtake, or canitakeover for a can-i-take-over-xyz fingerprints.json")
+	fingerprintListCmd.Flags().BoolVar(&listCount, "count", false, "print only the number of fingerprints")
+}
+
+func runFingerprintList(cmd *cobra.Command, args []string) error {
+	fp, err := fingerprints.Load(fingerprintsFile, importFormat)
+	if err != nil {
+		return fmt.Errorf("failed to load fingerprints: %w", err)
+	}
+
+	if listCount {
+		fmt.Println(len(fp.Fingerprints))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSERVICE\tPATTERN\tREGEX\tSTATE\tSOURCE")
+	for _, fingerprint := range fp.Fingerprints {
+		state := "enabled"
+		if !fingerprint.IsEnabled() {
+			state = "disabled"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\n", fingerprint.ID, fingerprint.Service,
+			truncatePattern(fingerprint.Describe(), 50), fingerprint.Regex, state, fingerprint.Source)
+	}
+	return w.Flush()
+}
+
+// truncatePattern shortens a pattern to max runes for table output
+func truncatePattern(pattern string, max int) string {
+	runes := []rune(pattern)
+	if len(runes) <= max {
+		return pattern
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+func runFingerprintNew(cmd *cobra.Command, args []string) error {
+	service, url := args[0], args[1]
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+
+	client, err := httpclient.New(buildConfig())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp := client.Get(context.Background(), url)
+	if resp.Error != nil {
+		return fmt.Errorf("failed to fetch %s: %w", url, resp.Error)
+	}
+
+	if scanner.IsBinary(resp.Headers.Get("Content-Type"), resp.Body) {
+		return fmt.Errorf("the response body of %s is binary, fingerprints only match text", url)
+	}
+
+	fingerprint := fingerprints.Suggest(service, resp.Body)
+	if fingerprint.Pattern == "" {
+		return fmt.Errorf("no suitable text found in the response body of %s", url)
+	}
+
+	fmt.Fprintf(os.Stderr, "# Fetched %s (status %d)\n", url, resp.StatusCode)
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+	return encoder.Encode([]fingerprints.Fingerprint{fingerprint})
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"log/slog"
+
+	"subtake/internal/dns"
+)
+
+// asciiInput forwards the entries of in with internationalized host names
+// converted to punycode, so "münchen.example.com" and
+// "xn--mnchen-3ya.example.com" are scanned as the same subdomain. Entries
+// that cannot be converted are skipped with a warning. rename is called with
+// each converted entry and its punycode form before it is forwarded.
+func asciiInput(in <-chan string, out chan<- string, rename func(entry, host string)) {
+	defer close(out)
+
+	for entry := range in {
+		ascii, err := dns.ToASCII(entry)
+		if err != nil {
+			slog.Warn("skipping invalid internationalized input entry", "entry", entry, "error", err)
+			continue
+		}
+		if ascii != entry {
+			rename(entry, ascii)
+		}
+		out <- ascii
+	}
+}
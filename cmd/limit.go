@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// defaultMaxSubdomains is the default cap on the number of distinct
+// subdomains in the input
+const defaultMaxSubdomains = 1_000_000
+
+// inputCap removes duplicate subdomains from the input and stops it at a
+// maximum number of distinct subdomains. Without truncate, exceeding the cap
+// is an error and the scan is stopped.
+type inputCap struct {
+	max      int
+	truncate bool
+	stop     func()
+
+	err error
+}
+
+// run forwards the distinct subdomains of in until the cap is exceeded, then
+// drains the rest of the input so the reader is not blocked
+func (c *inputCap) run(in <-chan string, out chan<- string) {
+	defer close(out)
+
+	seen := make(map[string]bool)
+	for subdomain := range in {
+		key := strings.ToLower(subdomain)
+		if seen[key] {
+			continue
+		}
+
+		if len(seen) == c.max {
+			if c.truncate {
+				slog.Warn("input exceeds --max-subdomains, scanning only the first subdomains", "max_subdomains", c.max)
+			} else {
+				c.err = fmt.Errorf("input has more than %s distinct subdomains; raise --max-subdomains or pass --truncate to scan only the first ones",
+					groupDigits(int64(c.max)))
+				c.stop()
+			}
+			for range in {
+			}
+			return
+		}
+
+		seen[key] = true
+		out <- subdomain
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"subtake/internal/types"
+
+	"github.com/spf13/cobra"
+)
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge <output.json> <input.json>...",
+	Short: "Merge several results files into one",
+	Long: `Merge combines results files from separate scans into a single report.
+Results are deduplicated by subdomain: a vulnerable result is preferred over a
+non-vulnerable one, and otherwise the most recent scan wins.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runMerge,
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.Flags().BoolVar(&legacyOutput, "legacy-output", false, "write a bare JSON array of results instead of the versioned results document")
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	outputPath, inputs := args[0], args[1:]
+
+	var sets [][]types.Result
+	total := 0
+	for _, input := range inputs {
+		results, err := loadScanResults(input)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", input, err)
+		}
+		sets = append(sets, results)
+		total += len(results)
+	}
+
+	merged := mergeResults(sets...)
+
+	file, err := createAtomicFile(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	defer file.Close()
+
+	// The merged report starts with the earliest of the merged scans
+	var started time.Time
+	for _, result := range merged {
+		if started.IsZero() || result.ScanTime.Before(started) {
+			started = result.ScanTime
+		}
+	}
+
+	writer, err := newResultsWriter(file, started)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	for _, result := range merged {
+		if err := writer.Write(result); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	fmt.Printf("Merged %d results from %d files into %d unique subdomains: %s\n", total, len(inputs), len(merged), outputPath)
+	return nil
+}
+
+// mergeResults deduplicates results by subdomain, keeping the order in which
+// subdomains were first seen. A vulnerable result beats a non-vulnerable one;
+// between equals the most recent scan wins.
+func mergeResults(sets ...[]types.Result) []types.Result {
+	var merged []types.Result
+	index := make(map[string]int)
+
+	for _, results := range sets {
+		for _, result := range results {
+			i, exists := index[result.Subdomain]
+			if !exists {
+				index[result.Subdomain] = len(merged)
+				merged = append(merged, result)
+				continue
+			}
+
+			if preferResult(result, merged[i]) {
+				merged[i] = result
+			}
+		}
+	}
+
+	return merged
+}
+
+// preferResult reports whether candidate should replace current
+func preferResult(candidate, current types.Result) bool {
+	if candidate.Vulnerable != current.Vulnerable {
+		return candidate.Vulnerable
+	}
+	return candidate.ScanTime.After(current.ScanTime)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"subtake/internal/types"
+)
+
+func TestPreferResult(t *testing.T) {
+	earlier := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		candidate types.Result
+		current   types.Result
+		want      bool
+	}{
+		{
+			name:      "vulnerable replaces not vulnerable",
+			candidate: types.Result{Vulnerable: true, ScanTime: earlier},
+			current:   types.Result{ScanTime: later},
+			want:      true,
+		},
+		{
+			name:      "not vulnerable keeps vulnerable, even when newer",
+			candidate: types.Result{ScanTime: later},
+			current:   types.Result{Vulnerable: true, ScanTime: earlier},
+			want:      false,
+		},
+		{
+			name:      "newer replaces older",
+			candidate: types.Result{Vulnerable: true, ScanTime: later},
+			current:   types.Result{Vulnerable: true, ScanTime: earlier},
+			want:      true,
+		},
+		{
+			name:      "older keeps newer",
+			candidate: types.Result{ScanTime: earlier},
+			current:   types.Result{ScanTime: later},
+			want:      false,
+		},
+		{
+			name:      "same time keeps the current",
+			candidate: types.Result{Vulnerable: true, ScanTime: earlier},
+			current:   types.Result{Vulnerable: true, ScanTime: earlier},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		if got := preferResult(tt.candidate, tt.current); got != tt.want {
+			t.Errorf("%s: preferResult = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	earlier := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	first := []types.Result{
+		{Subdomain: "a.example.com", Status: "vulnerable", Vulnerable: true, ScanTime: earlier},
+		{Subdomain: "b.example.com", Status: "not vulnerable", ScanTime: earlier},
+	}
+	second := []types.Result{
+		{Subdomain: "c.example.com", Status: "not vulnerable", ScanTime: later},
+		{Subdomain: "a.example.com", Status: "not vulnerable", ScanTime: later},
+		{Subdomain: "b.example.com", Status: "vulnerable", Vulnerable: true, ScanTime: later},
+	}
+
+	merged := mergeResults(first, second)
+	want := []struct {
+		subdomain string
+		status    string
+	}{
+		{"a.example.com", "vulnerable"},
+		{"b.example.com", "vulnerable"},
+		{"c.example.com", "not vulnerable"},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("mergeResults returned %d results, want %d", len(merged), len(want))
+	}
+	for i, w := range want {
+		if merged[i].Subdomain != w.subdomain || merged[i].Status != w.status {
+			t.Errorf("result %d = %s (%s), want %s (%s)", i, merged[i].Subdomain, merged[i].Status, w.subdomain, w.status)
+		}
+	}
+}
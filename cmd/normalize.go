@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// defaultPorts maps URL schemes to the port they imply
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// normalizeEntry reduces an input entry such as
+// "https://sub.example.com:8443/path" to the host the scanner expects,
+// "sub.example.com:8443". The port is kept only when it is not the default of
+// the scheme, or of either protocol for entries without a scheme, and is
+// always dropped with stripPort.
+func normalizeEntry(entry string, stripPort bool) (string, error) {
+	entry = strings.TrimSpace(entry)
+	scheme := ""
+	if i := strings.Index(entry, "://"); i >= 0 {
+		scheme = strings.ToLower(entry[:i])
+	} else {
+		entry = "//" + entry
+	}
+
+	u, err := url.Parse(entry)
+	if err != nil {
+		return "", err
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "", fmt.Errorf("no host")
+	}
+
+	port := u.Port()
+	switch {
+	case port == "" || stripPort:
+		return host, nil
+	case scheme == "" && (port == "80" || port == "443"):
+		return host, nil
+	case defaultPorts[scheme] == port:
+		return host, nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// normalizeInput forwards the normalized form of every entry of in, skipping
+// the entries that have no host with a warning. rename is called with each
+// entry and its normalized form before it is forwarded, so the data recorded
+// for the entry can follow it.
+func normalizeInput(in <-chan string, out chan<- string, stripPort bool, rename func(entry, host string)) {
+	defer close(out)
+
+	for entry := range in {
+		normalized, err := normalizeEntry(entry, stripPort)
+		if err != nil {
+			slog.Warn("skipping malformed input entry", "entry", entry, "error", err)
+			continue
+		}
+		rename(entry, normalized)
+		out <- normalized
+	}
+}
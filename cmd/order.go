@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"subtake/internal/types"
+)
+
+// sortOutputModes lists the supported values of --sort-output
+var sortOutputModes = []string{"discovery", "input", "alpha"}
+
+// inputOrder records the position of each subdomain in the input, so results
+// that complete out of order can be put back in input order
+type inputOrder struct {
+	mu        sync.RWMutex
+	positions map[string]int
+}
+
+func newInputOrder() *inputOrder {
+	return &inputOrder{positions: make(map[string]int)}
+}
+
+// run forwards the subdomains of in, recording the position of the first
+// occurrence of each
+func (o *inputOrder) run(in <-chan string, out chan<- string) {
+	defer close(out)
+
+	for subdomain := range in {
+		key := strings.ToLower(subdomain)
+		o.mu.Lock()
+		if _, ok := o.positions[key]; !ok {
+			o.positions[key] = len(o.positions)
+		}
+		o.mu.Unlock()
+		out <- subdomain
+	}
+}
+
+// sort sorts results in place by their position in the input. Results for
+// subdomains that were not in the input come last.
+func (o *inputOrder) sort(results []types.Result) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	position := func(subdomain string) int {
+		if p, ok := o.positions[strings.ToLower(subdomain)]; ok {
+			return p
+		}
+		return len(o.positions)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return position(results[i].Subdomain) < position(results[j].Subdomain)
+	})
+}
@@ -2,17 +2,33 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"runtime"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose bool
+	verbose   bool
+	noBanner  bool
+	logLevel  string
+	logFormat string
 )
 
-// showBanner displays the tool banner
+// Build information, set from main via SetVersionInfo
+var (
+	buildVersion = "dev"
+	buildCommit  = "none"
+)
+
+// showBanner displays the tool banner unless it was disabled or stdout is not
+// a terminal
 func showBanner() {
+	if noBanner || !isTerminal(os.Stdout) {
+		return
+	}
+
 	banner := `
 ███████╗██╗   ██╗██████╗ ████████╗ █████╗ ██╗  ██╗███████╗
 ██╔════╝██║   ██║██╔══██╗╚══██╔══╝██╔══██╗██║ ██╔╝██╔════╝
@@ -34,6 +50,9 @@ var rootCmd = &cobra.Command{
 	Long: `SubTake is a CLI tool for detecting subdomain takeover vulnerabilities.
 It scans subdomains against known hosting service fingerprints to identify
 potential takeover opportunities.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setupLogging()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		showBanner()
 		if len(args) == 0 {
@@ -43,11 +62,70 @@ potential takeover opportunities.`,
 	},
 }
 
+// setupLogging installs the default structured logger on stderr according to
+// the logging flags
+func setupLogging() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf("invalid log level %q", logLevel)
+	}
+
+	// Verbose is a shorthand for debug logging, quiet only lets errors through
+	if verbose {
+		level = slog.LevelDebug
+	}
+	if quiet {
+		level = slog.LevelError
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch logFormat {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("invalid log format %q (expected text or json)", logFormat)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// isTerminal reports whether the file is a character device such as a TTY
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetVersionInfo sets the build information embedded via ldflags
+func SetVersionInfo(version, commit string) {
+	buildVersion = version
+	buildCommit = commit
+	rootCmd.Version = versionString()
+}
+
+// versionString returns the version, commit and Go version of the build
+func versionString() string {
+	return fmt.Sprintf("%s (commit %s, %s)", buildVersion, buildCommit, runtime.Version())
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
 	return rootCmd.Execute()
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output for debugging")
+	rootCmd.Version = versionString()
+	rootCmd.SetVersionTemplate("subtake {{.Version}}\n")
+
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output for debugging (same as --log-level debug)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "log format (text, json)")
+	rootCmd.PersistentFlags().BoolVar(&noBanner, "no-banner", false, "do not print the banner")
 }
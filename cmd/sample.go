@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sampler selects a random subset of the deduplicated input before scanning
+type sampler struct {
+	percent float64 // share of the input to keep, 0 when count is used
+	count   int     // number of subdomains to keep, 0 when percent is used
+	seed    int64
+
+	total   int
+	sampled int
+}
+
+// newSampler parses the --sample and --sample-count flags. It returns nil when
+// no sampling was requested.
+func newSampler(sample string, count int, seed int64) (*sampler, error) {
+	if sample != "" && count > 0 {
+		return nil, fmt.Errorf("--sample and --sample-count are mutually exclusive")
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid --sample-count %d", count)
+	}
+
+	s := &sampler{count: count, seed: seed}
+	if sample != "" {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(sample), "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid --sample %q (expected a percentage such as 10%%)", sample)
+		}
+		s.percent = percent
+	}
+
+	if s.percent == 0 && s.count == 0 {
+		return nil, nil
+	}
+	if s.seed == 0 {
+		s.seed = time.Now().UnixNano()
+	}
+	return s, nil
+}
+
+// run reads the whole input, removes duplicates and forwards the sample in
+// input order
+func (s *sampler) run(in <-chan string, out chan<- string) {
+	defer close(out)
+
+	var list []string
+	seen := make(map[string]bool)
+	for subdomain := range in {
+		key := strings.ToLower(subdomain)
+		if !seen[key] {
+			seen[key] = true
+			list = append(list, subdomain)
+		}
+	}
+	s.total = len(list)
+
+	size := s.count
+	if s.percent > 0 {
+		size = int(math.Ceil(float64(s.total) * s.percent / 100))
+	}
+	size = min(size, s.total)
+	s.sampled = size
+
+	picked := rand.New(rand.NewSource(s.seed)).Perm(s.total)[:size]
+	sort.Ints(picked)
+	for _, index := range picked {
+		out <- list[index]
+	}
+}
+
+// String describes the sample for the scan summary
+func (s *sampler) String() string {
+	return fmt.Sprintf("sample of %s out of %s subdomains, seed %d", groupDigits(int64(s.sampled)), groupDigits(int64(s.total)), s.seed)
+}
@@ -1,15 +1,27 @@
 package cmd
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"subtake/internal/config"
+	"subtake/internal/dns"
 	"subtake/internal/fingerprints"
+	"subtake/internal/httpclient"
+	"subtake/internal/metrics"
+	"subtake/internal/output"
 	"subtake/internal/scanner"
 	"subtake/internal/types"
 
@@ -20,11 +32,95 @@ var (
 	listFile         string
 	outputFile       string
 	fingerprintsFile string
+	importFormat     string
 	userAgent        string
 	insecure         bool
 	rate             int
 	timeoutRetries   int
 	timeout          int
+	threshold        int
+	timeoutPerHost   int
+	quiet            bool
+	coalesceByCNAME  bool
+	saveBodiesDir    string
+	activeConfirm    bool
+	allowPost        bool
+	matchRaw         bool
+	sortBy           string
+	groupBy          string
+	matcherCmd       string
+	maxBackoff       int
+	cacheDir         string
+	cacheTTL         time.Duration
+	cacheRefresh     bool
+	ports            []int
+	inputFormat      string
+	inputColumn      string
+	maxFindings      int
+	userAgentsFile   string
+	tlsMinVersion    string
+	tlsMaxVersion    string
+	passive          bool
+	outputFormat     string
+	maxCNAMEDepth    int
+	cookies          []string
+	bearerToken      string
+	diffBodies       bool
+	ratePerHost      int
+	sample           string
+	sampleCount      int
+	sampleSeed       int64
+	outputFields     string
+	minSeverity      string
+	servicesFile     string
+	snippetWindow    int
+	allMatches       bool
+	noKeepAlive      bool
+	maxIdlePerHost   int
+	onlyErrors       bool
+	verify           bool
+	maxSubdomains    int
+	truncateInput    bool
+	archiveFile      string
+	sqliteFile       string
+	normalizeURL     bool
+	stripPorts       bool
+	legacyOutput     bool
+	excludeFile      string
+	excludeMode      string
+	sortOutput       string
+	retryEmpty       bool
+	compareIP        bool
+	cloudRangesFile  string
+	httpFallback     bool
+	acceptHeader     string
+	acceptJSON       bool
+	headers          []string
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	failFastAfter    int
+	metricsAddr      string
+	firstMatch       bool
+	matchPolicy      string
+	timingBreakdown  bool
+	maxCIDRAddresses int
+	delay            time.Duration
+	redact           bool
+	binaryBodies     string
+	slowThreshold    time.Duration
+	maxConnections   int
+	legitMinBytes    int
+	legitMinLinks    int
+	probeConcurrency int
+	redactPatterns   []string
+	probeWWWVariant  bool
+	chunkSize        int
+	resumeScan       bool
+	outputAll        bool
+	trimClusterSize  int
+	orderedOutput    bool
+	baselineFile     string
+	since            time.Duration
 )
 
 // scanCmd represents the scan command
@@ -42,125 +138,1005 @@ func init() {
 	rootCmd.AddCommand(scanCmd)
 
 	scanCmd.Flags().StringVarP(&listFile, "list", "l", "", "file containing subdomains (one per line)")
-	scanCmd.Flags().StringVarP(&outputFile, "output", "o", "", "output file for results (JSON format)")
-	scanCmd.Flags().StringVar(&fingerprintsFile, "fingerprints", "", "custom fingerprints file (JSON/YAML)")
-	scanCmd.Flags().StringVar(&userAgent, "user-agent", "SubTake/1.0", "user agent string for requests")
-	scanCmd.Flags().BoolVar(&insecure, "insecure", false, "allow insecure TLS connections")
+	scanCmd.Flags().StringVarP(&outputFile, "output", "o", "", "output file for results")
+	scanCmd.Flags().StringVar(&outputFormat, "format", "json", "output file format: json, html, or txt for one vulnerable subdomain per line (to stdout without -o)")
+	scanCmd.Flags().BoolVar(&outputAll, "output-all", false, "write every result to the output file, including not vulnerable and errored subdomains, instead of only findings")
+	scanCmd.Flags().BoolVar(&legacyOutput, "legacy-output", false, "write a bare JSON array of results instead of the versioned results document")
+	scanCmd.Flags().StringVar(&outputFields, "fields", "", "comma-separated result fields to write to JSON output, e.g. subdomain,status,service,cname,confidence (default all)")
+	scanCmd.Flags().StringVar(&inputFormat, "input-format", "lines", "format of the list file: lines, csv, pairs (ip,hostname) or cidr (CIDR blocks expanded by reverse DNS)")
+	scanCmd.Flags().IntVar(&maxCIDRAddresses, "max-cidr-addresses", 65536, "maximum number of addresses a cidr list may expand to")
+	scanCmd.Flags().StringVar(&inputColumn, "input-column", "", "CSV column holding the subdomain, by header name or 0-based index (default first column)")
 	scanCmd.Flags().IntVar(&rate, "rate", 0, "requests per second limit (0 = no limit)")
-	scanCmd.Flags().IntVar(&timeoutRetries, "timeout-retries", 1, "number of retries on timeout")
-	scanCmd.Flags().IntVar(&timeout, "timeout", 10, "request timeout in seconds")
+	scanCmd.Flags().DurationVar(&delay, "delay", 0, "fixed pause between requests across all workers, e.g. 250ms (alternative to --rate)")
+	scanCmd.MarkFlagsMutuallyExclusive("rate", "delay")
+	scanCmd.Flags().IntVar(&ratePerHost, "rate-per-host", 0, "requests per second limit for each apex domain (0 = no limit)")
+	scanCmd.Flags().BoolVar(&coalesceByCNAME, "coalesce-by-cname", false, "probe subdomains sharing a CNAME target once and infer the rest")
+	scanCmd.Flags().StringVar(&saveBodiesDir, "save-bodies", "", "directory to save the full response body of each vulnerable subdomain to")
+	scanCmd.Flags().StringVar(&sortBy, "sort-by", "", "sort the output file by subdomain, service, confidence, status or error_type")
+	scanCmd.Flags().StringVar(&sortOutput, "sort-output", "discovery", "order of the output file: discovery (as results complete), input or alpha")
+	scanCmd.Flags().BoolVar(&orderedOutput, "ordered", false, "print and write results in input order as the scan advances, holding back those that complete early")
+	scanCmd.Flags().StringVar(&groupBy, "group-by", "", "list findings grouped by service after the scan (service)")
+	scanCmd.Flags().StringVar(&sample, "sample", "", "scan a random sample of this percentage of the deduplicated input, e.g. 10%")
+	scanCmd.Flags().IntVar(&sampleCount, "sample-count", 0, "scan a random sample of this many deduplicated subdomains")
+	scanCmd.Flags().Int64Var(&sampleSeed, "seed", 0, "random seed for --sample and --sample-count, for a reproducible sample (0 = random)")
+	scanCmd.Flags().BoolVar(&normalizeURL, "normalize-url", false, "reduce input entries given as URLs (https://sub.example.com:8443/path) to their host, keeping non-default ports")
+	scanCmd.Flags().BoolVar(&stripPorts, "strip-ports", false, "with --normalize-url, drop every port from the input entries")
+	scanCmd.Flags().StringVar(&excludeFile, "exclude-file", "", "file of known-safe subdomains or glob patterns (*.parked.example.com) that are not scanned")
+	scanCmd.Flags().StringVar(&baselineFile, "baseline", "", "previous results file; with --since, its subdomains with a recent result are not scanned again and their results are carried over")
+	scanCmd.Flags().DurationVar(&since, "since", 0, "with --baseline, scan again only the subdomains whose last result is older than this, e.g. 24h")
+	scanCmd.MarkFlagsRequiredTogether("baseline", "since")
+	scanCmd.Flags().StringVar(&excludeMode, "exclude-mode", "skip", "what to do with excluded subdomains: skip them, or mark them as not vulnerable in the results")
+	scanCmd.Flags().IntVar(&maxSubdomains, "max-subdomains", defaultMaxSubdomains, "maximum number of distinct subdomains to accept from the input (0 = no limit)")
+	scanCmd.Flags().BoolVar(&truncateInput, "truncate", false, "scan only the first --max-subdomains subdomains of a larger input instead of failing")
+	scanCmd.Flags().StringVar(&archiveFile, "archive", "", "record the requests and responses of every probed subdomain in this HAR file")
+	scanCmd.Flags().StringVar(&sqliteFile, "sqlite", "", "also store the results in this SQLite database, appending the scan as a new run")
+	scanCmd.Flags().IntVar(&breakerThreshold, "breaker-threshold", 0, "skip the remaining subdomains of an apex after this many consecutive timeouts or connection failures (0 = off)")
+	scanCmd.Flags().DurationVar(&breakerCooldown, "breaker-cooldown", 0, "probe a skipped apex again after this long, e.g. 2m (0 = skip it for the rest of the scan)")
+	scanCmd.Flags().IntVar(&failFastAfter, "fail-fast", 0, "abort the scan after this many consecutive operational errors, such as an unreachable resolver or network (0 = off)")
+	scanCmd.Flags().BoolVar(&redact, "redact", false, "replace secrets (AWS keys, JWTs, bearer tokens, email addresses) in stored bodies and snippets with [REDACTED]")
+	scanCmd.Flags().StringArrayVar(&redactPatterns, "redact-pattern", nil, "additional regex to redact from stored bodies and snippets, implies --redact (repeatable)")
+	scanCmd.Flags().BoolVar(&probeWWWVariant, "probe-www", false, "also scan www.<host> for every input host without a www. prefix")
+	scanCmd.Flags().IntVar(&chunkSize, "chunk-size", 0, "scan the list in chunks of this many subdomains, checkpointing the output file after each chunk (0 = off)")
+	scanCmd.Flags().BoolVar(&resumeScan, "resume", false, "resume an interrupted chunked scan after its last completed chunk")
+	scanCmd.Flags().BoolVar(&verify, "verify", false, "query the DNS records of each finding right away and embed the answers in the result")
+	scanCmd.Flags().BoolVar(&onlyErrors, "only-errors", false, "only print and write results that failed with an error, grouped by error type")
+	scanCmd.MarkFlagsMutuallyExclusive("output-all", "only-errors")
+	scanCmd.Flags().StringVar(&minSeverity, "min-severity", "", "only report findings of at least this severity: low, medium, high or critical")
+	scanCmd.Flags().IntVar(&maxFindings, "max-findings", 0, "stop the scan after this many vulnerable subdomains (0 = no limit)")
+	scanCmd.Flags().IntVar(&trimClusterSize, "trim-cdn-wildcards", 0, "after the scan, downgrade findings with generic or weak evidence only that share an identical response body with at least this many findings in total (0 = off)")
+	scanCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "serve Prometheus metrics of the running scan on this address, e.g. :9090")
+	scanCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "print only errors and a final one-line summary")
+	addProbeFlags(scanCmd)
+}
+
+// addProbeFlags registers the flags that control how targets are probed.
+// They are shared by every command that sends requests.
+func addProbeFlags(c *cobra.Command) {
+	c.Flags().StringVar(&fingerprintsFile, "fingerprints", "", "custom fingerprints file (JSON/YAML)")
+	c.Flags().StringVar(&importFormat, "import-format", fingerprints.FormatSubtake, "format of the custom fingerprints file: subtake, or canitakeover for a can-i-take-over-xyz fingerprints.json")
+	c.Flags().StringVar(&servicesFile, "services", "", "custom service registry file (JSON/YAML)")
+	c.Flags().StringVar(&userAgent, "user-agent", "SubTake/1.0", "user agent string for requests")
+	c.Flags().StringVar(&userAgentsFile, "user-agents", "", "file of user agent strings (one per line) to rotate through per request")
+	c.Flags().StringArrayVar(&cookies, "cookie", nil, "cookie to send with every request as name=value (repeatable)")
+	c.Flags().StringVar(&bearerToken, "bearer", "", "bearer token to send in the Authorization header")
+	c.Flags().StringArrayVarP(&headers, "header", "H", nil, "header to send with every request as \"Name: value\", overriding the defaults (repeatable)")
+	c.Flags().StringVar(&acceptHeader, "accept", httpclient.DefaultAccept, "Accept header to send")
+	c.Flags().BoolVar(&acceptJSON, "accept-json", false, "send Accept: application/json, for API endpoints that only show their error as JSON")
+	c.MarkFlagsMutuallyExclusive("accept", "accept-json")
+	c.Flags().BoolVar(&insecure, "insecure", false, "allow insecure TLS connections")
+	c.Flags().StringVar(&tlsMinVersion, "tls-min-version", "", "minimum TLS version to offer: 1.0, 1.1, 1.2 or 1.3 (default Go's minimum, 1.2)")
+	c.Flags().StringVar(&tlsMaxVersion, "tls-max-version", "", "maximum TLS version to offer: 1.0, 1.1, 1.2 or 1.3")
+	c.Flags().IntVar(&timeoutRetries, "timeout-retries", 1, "number of retries on timeout")
+	c.Flags().BoolVar(&retryEmpty, "retry-empty", false, "also retry successful responses with an empty body, within the --timeout-retries budget")
+	c.Flags().IntVar(&timeout, "timeout", 10, "request timeout in seconds")
+	c.Flags().IntVar(&maxBackoff, "max-backoff", 30, "maximum wait between retries in seconds, including Retry-After delays")
+	c.Flags().IntVar(&timeoutPerHost, "timeout-per-host", 0, "total time budget per subdomain in seconds, across protocols and retries (0 = no limit)")
+	c.Flags().IntVar(&maxCNAMEDepth, "max-cname-depth", dns.DefaultMaxCNAMEDepth, "maximum number of CNAME hops to follow; deeper chains and loops are flagged as DNS anomalies")
+	c.Flags().BoolVar(&passive, "passive", false, "only use DNS: report dangling CNAMEs and known service targets without sending HTTP requests")
+	c.Flags().BoolVar(&compareIP, "compare-ip", false, "flag subdomains whose address is in a cloud provider range (AWS, Google Cloud, Azure) but serves nothing")
+	c.Flags().StringVar(&cloudRangesFile, "cloud-ranges", "", "additional cloud provider ranges for --compare-ip (JSON/YAML)")
+	c.Flags().DurationVar(&slowThreshold, "slow-threshold", 0, "flag subdomains with a response that took longer than this, e.g. 8s, as slow (0 = off)")
+	c.Flags().BoolVar(&diffBodies, "diff-bodies", false, "flag subdomains whose HTTP and HTTPS responses differ (protocol_mismatch)")
+	c.Flags().BoolVar(&activeConfirm, "active-confirm", false, "send the active confirmation probes of matched fingerprints (more intrusive)")
+	c.Flags().BoolVar(&matchRaw, "match-raw", false, "match fingerprints against the raw bytes of responses that are not valid HTTP, e.g. HTTP/0.9")
+	c.Flags().BoolVar(&allowPost, "allow-post", false, "send the POST (or other) requests of fingerprints that define one and match them against the response (more intrusive)")
+	c.Flags().StringVar(&matcherCmd, "matcher-cmd", "", "external command that receives each response as JSON on stdin and prints a JSON verdict")
+	c.Flags().BoolVar(&httpFallback, "http-version-fallback", true, "repeat requests that fail with an HTTP/2 protocol error over HTTP/1.1")
+	c.Flags().BoolVar(&noKeepAlive, "no-keepalive", false, "close every connection after its request instead of keeping it for reuse")
+	c.Flags().IntVar(&maxConnections, "max-connections", 0, "maximum number of HTTP requests in flight at once across the 20 scan workers, bounding open connections (0 = no limit)")
+	c.Flags().IntVar(&maxIdlePerHost, "max-idle-per-host", httpclient.DefaultMaxIdleConnsPerHost, "maximum number of idle connections kept per host")
+	c.Flags().IntVar(&probeConcurrency, "probe-concurrency", 2, "number of protocols and ports of a subdomain probed at once; 1 probes HTTPS, then HTTP, then the other ports one after the other")
+	c.Flags().IntSliceVar(&ports, "ports", nil, "ports to probe, e.g. 80,443,8080,8443 (ports ending in 443 use HTTPS; default 443 and 80)")
+	c.Flags().StringVar(&cacheDir, "cache-dir", "", "directory to cache responses in, so repeated scans reuse them")
+	c.Flags().DurationVar(&cacheTTL, "cache-ttl", 24*time.Hour, "how long cached responses stay valid")
+	c.Flags().BoolVar(&cacheRefresh, "refresh", false, "ignore cached responses and fetch again (the cache is still updated)")
+	c.Flags().IntVar(&snippetWindow, "snippet-window", scanner.DefaultSnippetWindow, "number of body characters kept on each side of a match in evidence snippets")
+	c.Flags().StringVar(&binaryBodies, "binary-bodies", scanner.BinaryHex, "how to store binary response bodies: a hex or base64 summary of their first bytes, or omit them")
+	c.Flags().BoolVar(&timingBreakdown, "timing", false, "record the DNS, connect, TLS and time-to-first-byte durations of every request")
+	c.Flags().BoolVar(&firstMatch, "first-match", false, "stop evaluating fingerprints once the matches of a response reach --threshold, keeping less evidence for speed")
+	c.Flags().StringVar(&matchPolicy, "match-policy", scanner.MatchAll, "which matches of a response become evidence: all, per-service for the highest-weight match of each service, or first to stop at the first match")
+	c.Flags().BoolVar(&allMatches, "all-matches", false, "record a snippet for every distinct match of a fingerprint, not just the first")
+	c.Flags().IntVar(&threshold, "threshold", fingerprints.DefaultThreshold, "minimum evidence score for a subdomain to be reported vulnerable")
+	c.Flags().IntVar(&legitMinBytes, "legit-min-bytes", scanner.DefaultLegitMinBytes, "body size from which a 200 response with enough links is a likely legit site that needs CNAME or service specific evidence (0 = off)")
+	c.Flags().IntVar(&legitMinLinks, "legit-min-links", scanner.DefaultLegitMinLinks, "number of links from which a large 200 response is a likely legit site")
+}
+
+// buildConfig creates the scanner configuration from the command line flags
+func buildConfig() *config.Config {
+	accept := acceptHeader
+	if acceptJSON {
+		accept = "application/json"
+	}
+
+	return &config.Config{
+		UserAgent:        userAgent,
+		Insecure:         insecure,
+		Rate:             rate,
+		Delay:            delay,
+		TimeoutRetries:   timeoutRetries,
+		Timeout:          time.Duration(timeout) * time.Second,
+		HostTimeout:      time.Duration(timeoutPerHost) * time.Second,
+		MaxBackoff:       time.Duration(maxBackoff) * time.Second,
+		Threshold:        threshold,
+		CoalesceByCNAME:  coalesceByCNAME,
+		KeepFullBody:     saveBodiesDir != "",
+		ActiveConfirm:    activeConfirm,
+		AllowPost:        allowPost,
+		MatchRaw:         matchRaw,
+		MatcherCmd:       matcherCmd,
+		CacheDir:         cacheDir,
+		CacheTTL:         cacheTTL,
+		CacheRefresh:     cacheRefresh,
+		Ports:            ports,
+		UserAgentsFile:   userAgentsFile,
+		TLSMinVersion:    tlsMinVersion,
+		TLSMaxVersion:    tlsMaxVersion,
+		Passive:          passive,
+		MaxCNAMEDepth:    maxCNAMEDepth,
+		Cookies:          cookies,
+		BearerToken:      bearerToken,
+		DiffBodies:       diffBodies,
+		RatePerHost:      ratePerHost,
+		ServicesFile:     servicesFile,
+		SnippetWindow:    snippetWindow,
+		AllMatches:       allMatches,
+		NoKeepAlive:      noKeepAlive,
+		MaxIdlePerHost:   maxIdlePerHost,
+		RetryEmpty:       retryEmpty,
+		CompareIP:        compareIP,
+		CloudRangesFile:  cloudRangesFile,
+		HTTPFallback:     httpFallback,
+		Accept:           accept,
+		Headers:          headers,
+		FirstMatch:       firstMatch,
+		MatchPolicy:      matchPolicy,
+		Timing:           timingBreakdown,
+		BinaryBodies:     binaryBodies,
+		SlowThreshold:    slowThreshold,
+		MaxConnections:   maxConnections,
+		LegitMinBytes:    legitMinBytes,
+		LegitMinLinks:    legitMinLinks,
+		ProbeConcurrency: probeConcurrency,
+	}
 }
 
 func runScan(cmd *cobra.Command, args []string) error {
-	// Show banner
-	showBanner()
+	started := time.Now()
+
+	// With --format txt the subdomains are the only output, so that the scan
+	// can be a stage of a pipeline
+	plainText := outputFormat == "txt"
+	if !quiet && !plainText {
+		showBanner()
+	}
+
 	// Validate input
-	if listFile == "" && len(args) == 0 {
-		return fmt.Errorf("must provide either a subdomain argument or use -l/--list")
+	if listFile == "" && len(args) == 0 && baselineFile == "" {
+		return fmt.Errorf("must provide either a subdomain argument or use -l/--list or --baseline")
+	}
+	if sortBy != "" && !output.ValidSortKey(sortBy) {
+		return fmt.Errorf("invalid --sort-by %q (expected one of %s)", sortBy, strings.Join(output.SortKeys, ", "))
+	}
+	if !slices.Contains(sortOutputModes, sortOutput) {
+		return fmt.Errorf("invalid --sort-output %q (expected one of %s)", sortOutput, strings.Join(sortOutputModes, ", "))
+	}
+	if sortBy != "" && sortOutput != "discovery" {
+		return fmt.Errorf("--sort-output cannot be used with --sort-by")
+	}
+	if groupBy != "" && groupBy != "service" {
+		return fmt.Errorf("invalid --group-by %q (expected service)", groupBy)
+	}
+	if outputFormat != "json" && outputFormat != "html" && !plainText {
+		return fmt.Errorf("invalid --format %q (expected json, html or txt)", outputFormat)
+	}
+	if onlyErrors && outputFormat == "html" {
+		return fmt.Errorf("--only-errors cannot be used with --format html")
+	}
+	if outputAll && outputFormat == "html" {
+		return fmt.Errorf("--output-all cannot be used with --format html, which reports findings only")
+	}
+	if chunkSize < 0 {
+		return fmt.Errorf("invalid --chunk-size %d (expected 0 or more)", chunkSize)
+	}
+	if since < 0 {
+		return fmt.Errorf("invalid --since %s (expected a positive duration)", since)
+	}
+	if failFastAfter < 0 {
+		return fmt.Errorf("invalid --fail-fast %d (expected 0 or more)", failFastAfter)
+	}
+	if trimClusterSize < 0 {
+		return fmt.Errorf("invalid --trim-cdn-wildcards %d (expected 0 or more)", trimClusterSize)
+	}
+	if trimClusterSize > 0 && onlyErrors {
+		return fmt.Errorf("--trim-cdn-wildcards cannot be used with --only-errors, which reports no findings")
+	}
+	var resumeFrom *checkpoint
+	if resumeScan {
+		if outputFile == "" {
+			return fmt.Errorf("--resume requires -o/--output")
+		}
+		cp, err := loadCheckpoint(checkpointFile(outputFile))
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		if cp.List != listFile {
+			return fmt.Errorf("checkpoint of %s is for list %q, not %q", outputFile, cp.List, listFile)
+		}
+		if chunkSize == 0 {
+			chunkSize = cp.ChunkSize
+		}
+		// The processed subdomains are counted in the sample, which only the
+		// same seed draws again
+		switch {
+		case cp.Seed != 0 && sampleSeed == 0:
+			sampleSeed = cp.Seed
+		case cp.Seed != 0 && sampleSeed != cp.Seed:
+			return fmt.Errorf("checkpoint of %s is for a sample with seed %d, not %d", outputFile, cp.Seed, sampleSeed)
+		}
+		resumeFrom = cp
+		started = cp.ScanStartedAt
+	}
+	if chunkSize > 0 {
+		switch {
+		case listFile == "":
+			return fmt.Errorf("--chunk-size requires -l/--list")
+		case outputFile == "" || outputFormat != "json":
+			return fmt.Errorf("--chunk-size requires a JSON output file (-o)")
+		case sortBy != "" || sortOutput != "discovery" || groupBy != "" || onlyErrors || trimClusterSize > 0:
+			return fmt.Errorf("--chunk-size cannot be used with --sort-by, --sort-output, --group-by, --only-errors or --trim-cdn-wildcards, which hold results back until the scan ends")
+		case baselineFile != "":
+			return fmt.Errorf("--chunk-size cannot be used with --baseline")
+		case resumeScan && (archiveFile != "" || sqliteFile != ""):
+			return fmt.Errorf("--resume cannot be used with --archive or --sqlite")
+		}
+	}
+	if inputFormat != "lines" && inputFormat != "csv" && inputFormat != "pairs" && inputFormat != "cidr" {
+		return fmt.Errorf("invalid --input-format %q (expected lines, csv, pairs or cidr)", inputFormat)
+	}
+	if excludeMode != "skip" && excludeMode != "mark" {
+		return fmt.Errorf("invalid --exclude-mode %q (expected skip or mark)", excludeMode)
+	}
+
+	smp, err := newSampler(sample, sampleCount, sampleSeed)
+	if err != nil {
+		return err
+	}
+	if resumeFrom != nil && (smp != nil) != (resumeFrom.Seed != 0) {
+		return fmt.Errorf("--resume needs the same --sample or --sample-count as the interrupted scan")
+	}
+	if minSeverity != "" && fingerprints.SeverityRank(minSeverity) == 0 {
+		return fmt.Errorf("invalid --min-severity %q (expected one of %s)", minSeverity, strings.Join(fingerprints.Severities, ", "))
+	}
+	fields, err := output.ParseFields(outputFields)
+	if err != nil {
+		return fmt.Errorf("invalid --fields: %w", err)
 	}
 
 	// Load configuration
-	cfg := &config.Config{
-		UserAgent:      userAgent,
-		Insecure:       insecure,
-		Rate:           rate,
-		TimeoutRetries: timeoutRetries,
-		Timeout:        time.Duration(timeout) * time.Second,
-		Verbose:        verbose,
+	cfg := buildConfig()
+	cfg.Verify = verify
+	cfg.BreakerThreshold = breakerThreshold
+	cfg.BreakerCooldown = breakerCooldown
+	cfg.Redact = redact
+	cfg.RedactPatterns = redactPatterns
+	cfg.Ordered = orderedOutput
+	if inputFormat == "pairs" {
+		cfg.Pins = dns.NewPins()
 	}
 
 	// Load fingerprints
-	fp, err := fingerprints.Load(fingerprintsFile)
+	fp, err := fingerprints.Load(fingerprintsFile, importFormat)
 	if err != nil {
 		return fmt.Errorf("failed to load fingerprints: %w", err)
 	}
 
-	// Get subdomains to scan
-	var subdomains []string
+	var baseline *baselineFilter
+	var baselineResults []types.Result
+	if baselineFile != "" {
+		baselineResults, err = loadScanResults(baselineFile)
+		if err != nil {
+			return fmt.Errorf("failed to load baseline: %w", err)
+		}
+		baseline = newBaselineFilter(baselineResults, time.Now().Add(-since))
+	}
+
+	var excluder *exclusionFilter
+	if excludeFile != "" {
+		list, err := loadExclusions(excludeFile)
+		if err != nil {
+			return fmt.Errorf("failed to load exclusions: %w", err)
+		}
+		excluder = &exclusionFilter{list: list}
+	}
+
+	// Stream subdomains to the scanner, collecting the tags given with them
+	subdomains := make(chan string)
+	tags := newInputTags()
+	var inputErr error
 	if listFile != "" {
-		subdomains, err = loadSubdomainsFromFile(listFile)
+		file, err := os.Open(listFile)
 		if err != nil {
 			return fmt.Errorf("failed to load subdomains from file: %w", err)
 		}
+		defer file.Close()
+
+		go func() {
+			switch inputFormat {
+			case "csv":
+				inputErr = readCSVSubdomains(file, inputColumn, subdomains)
+			case "pairs":
+				inputErr = readPairs(file, cfg.Pins, tags, subdomains)
+			case "cidr":
+				inputErr = readCIDRs(file, maxCIDRAddresses, tags, subdomains)
+			default:
+				inputErr = readSubdomains(file, tags, subdomains)
+			}
+			close(subdomains)
+		}()
 	} else {
-		subdomains = []string{args[0]}
+		go func() {
+			if len(args) > 0 {
+				subdomains <- args[0]
+			} else {
+				baselineSubdomains(baselineResults, subdomains)
+			}
+			close(subdomains)
+		}()
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Loaded %d subdomains to scan\n", len(subdomains))
-		fmt.Fprintf(os.Stderr, "Loaded %d fingerprints\n", len(fp.Fingerprints))
+	// The scan is cancelled once enough findings were collected, the input
+	// exceeds the cap or operational errors keep piling up
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var aborter *failFast
+	if failFastAfter > 0 {
+		aborter = &failFast{threshold: failFastAfter, stop: cancel}
 	}
 
-	// Create scanner
-	s := scanner.New(cfg, fp)
+	// The pinned address and tags of an entry follow it when it is rewritten
+	rename := func(entry, host string) {
+		if ip, ok := cfg.Pins.Lookup(entry); ok {
+			cfg.Pins.Set(host, ip)
+		}
+		if entryTags := tags.get(entry); entryTags != nil {
+			tags.set(host, entryTags)
+		}
+	}
+
+	// Entries are normalized first and converted to punycode, www variants
+	// added, excluded subdomains and those with a fresh baseline result
+	// dropped; only the distinct subdomains up to the cap, and of those only
+	// the sample, are passed on to the scanner
+	scanInput := subdomains
+	if normalizeURL {
+		normalized := make(chan string)
+		go normalizeInput(scanInput, normalized, stripPorts, rename)
+		scanInput = normalized
+	}
+	ascii := make(chan string)
+	go asciiInput(scanInput, ascii, rename)
+	scanInput = ascii
+	if probeWWWVariant {
+		expanded := make(chan string)
+		go probeWWW(scanInput, expanded, func(variant, subdomain string) {
+			if subdomainTags := tags.get(subdomain); subdomainTags != nil {
+				tags.set(variant, subdomainTags)
+			}
+		})
+		scanInput = expanded
+	}
+	if excluder != nil {
+		filtered := make(chan string)
+		go excluder.run(scanInput, filtered)
+		scanInput = filtered
+	}
+	if baseline != nil {
+		stale := make(chan string)
+		go baseline.run(scanInput, stale)
+		scanInput = stale
+	}
+	var capper *inputCap
+	if maxSubdomains > 0 {
+		capper = &inputCap{max: maxSubdomains, truncate: truncateInput, stop: cancel}
+		capped := make(chan string)
+		go capper.run(scanInput, capped)
+		scanInput = capped
+	}
+	if smp != nil {
+		sampled := make(chan string)
+		go smp.run(scanInput, sampled)
+		scanInput = sampled
+	}
+	var order *inputOrder
+	if sortOutput == "input" {
+		order = newInputOrder()
+		ordered := make(chan string)
+		go order.run(scanInput, ordered)
+		scanInput = ordered
+	}
 
-	// Scan subdomains with real-time output
-	results := s.ScanWithRealtimeOutput(subdomains)
+	slog.Info("loaded fingerprints", "fingerprints", len(fp.Fingerprints))
 
-	// Output results to file if specified
+	// JSON results are written to the output file as they arrive, and so
+	// are the subdomains with --format txt
+	// The output file replaces the target once complete, except for chunked
+	// scans, which checkpoint the file itself
+	var outFile *os.File
+	var atomicOut *atomicFile
+	var writer *output.JSONArrayWriter
+	var lines io.Writer
+	if plainText {
+		lines = os.Stdout
+	}
 	if outputFile != "" {
-		vulnerableCount := 0
-		for _, result := range results {
-			if result.Vulnerable && result.Status == "vulnerable" {
-				vulnerableCount++
+		switch {
+		case resumeFrom != nil:
+			outFile, err = openResumedOutput(outputFile, resumeFrom.Offset)
+		case chunkSize > 0:
+			outFile, err = createOutputFile(outputFile)
+		default:
+			atomicOut, err = createAtomicFile(outputFile)
+			if err == nil {
+				outFile = atomicOut.File
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		if atomicOut != nil {
+			defer atomicOut.Close()
+		} else {
+			defer outFile.Close()
+		}
+		if plainText {
+			lines = outFile
+		}
+		if outputFormat == "json" {
+			writer, err = newResultsWriter(outFile, started)
+			if err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+		}
+		if resumeFrom != nil {
+			writer.Resume(resumeFrom.Written)
+		}
+	}
+
+	// A chunked scan checkpoints its progress next to the output file; the
+	// checkpoint of an earlier scan does not apply to a new one
+	var progress *checkpoint
+	if chunkSize > 0 {
+		progress = resumeFrom
+		if progress == nil {
+			progress = &checkpoint{List: listFile, ChunkSize: chunkSize, ScanStartedAt: started}
+			if smp != nil {
+				progress.Seed = smp.seed
 			}
+			if err := os.Remove(checkpointFile(outputFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove checkpoint: %w", err)
+			}
+		}
+	}
+
+	// Every exchange is archived as it arrives, whatever its verdict
+	var archive *output.HARWriter
+	var archiveOut *atomicFile
+	if archiveFile != "" {
+		archiveOut, err = createAtomicFile(archiveFile)
+		if err != nil {
+			return fmt.Errorf("failed to create archive file: %w", err)
 		}
+		defer archiveOut.Close()
+		archive = output.NewHARWriter(archiveOut, buildVersion)
+	}
 
-		err = outputToFile(results, outputFile)
+	// Results are stored as they are recorded, each scan as a new run
+	var store *output.SQLiteWriter
+	if sqliteFile != "" {
+		if err := os.MkdirAll(filepath.Dir(sqliteFile), 0755); err != nil {
+			return fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		input := listFile
+		switch {
+		case input == "" && len(args) > 0:
+			input = args[0]
+		case input == "":
+			input = baselineFile
+		}
+		store, err = output.NewSQLiteWriter(sqliteFile, buildVersion, input, started)
 		if err != nil {
+			return fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+		defer store.Close()
+	}
+
+	if saveBodiesDir != "" {
+		if err := os.MkdirAll(saveBodiesDir, 0755); err != nil {
+			return fmt.Errorf("failed to create bodies directory: %w", err)
+		}
+	}
+
+	// Create scanner
+	s, err := scanner.New(cfg, fp)
+	if err != nil {
+		return fmt.Errorf("failed to create scanner: %w", err)
+	}
+	defer s.Cleanup()
+
+	var scanMetrics *metrics.Metrics
+	if metricsAddr != "" {
+		scanMetrics = metrics.New(s.Requests)
+		stop, err := scanMetrics.Serve(metricsAddr)
+		if err != nil {
+			return fmt.Errorf("failed to serve metrics: %w", err)
+		}
+		defer stop()
+	}
+
+	// Scan subdomains with real-time output unless running quietly, with a
+	// live tally on stderr when it is a terminal
+	var counts tally
+	var writeErr, archiveErr, storeErr error
+
+	var status *statusLine
+	if !quiet && !plainText && isTerminal(os.Stderr) {
+		status = startStatusLine(os.Stderr, &counts)
+	}
+
+	// Findings are held back when they have to be sorted, grouped, clustered
+	// or rendered as a whole. With --only-errors they are the errored results.
+	// Otherwise they are written as they complete, which is also the order
+	// of the console output.
+	var findings []types.Result
+	keepFindings := sortBy != "" || sortOutput != "discovery" || groupBy != "" || outputFormat == "html" || onlyErrors || trimClusterSize > 0
+
+	record := func(result types.Result) {
+		if keepFindings {
+			findings = append(findings, result)
+		} else if writer != nil && writeErr == nil {
+			writeErr = writer.Write(output.Project(result, fields))
+		} else if lines != nil && writeErr == nil {
+			_, writeErr = fmt.Fprintln(lines, result.Subdomain)
+		}
+		if store != nil && !keepFindings && storeErr == nil {
+			storeErr = store.Write(result)
+		}
+	}
+
+	// Only vulnerable results are written to the output file, unless
+	// --output-all asks for the complete record of the scan
+	isFinding := func(result types.Result) bool {
+		return result.Vulnerable && result.Status == "vulnerable" &&
+			fingerprints.SeverityRank(result.Severity) >= fingerprints.SeverityRank(minSeverity)
+	}
+
+	var timings timingStats
+	emit := func(result types.Result) {
+		result.Tags = tags.get(result.Subdomain)
+		counts.scanned.Add(1)
+		if result.Slow {
+			counts.slow.Add(1)
+		}
+		if timingBreakdown {
+			timings.add(result)
+		}
+		if scanMetrics != nil {
+			scanMetrics.Observe(result)
+		}
+		if aborter != nil {
+			aborter.record(result)
+		}
+		if archive != nil && archiveErr == nil {
+			archiveErr = archive.Write(result)
+		}
+		if !quiet && !plainText && (!onlyErrors || result.Status == "error") {
+			if status != nil {
+				status.print(func() { s.PrintResult(result) })
+			} else {
+				s.PrintResult(result)
+			}
+		}
+
+		if onlyErrors {
+			if result.Status == "error" {
+				counts.errored.Add(1)
+				findings = append(findings, result)
+			}
+			return
+		}
+
+		if !isFinding(result) {
+			if outputAll {
+				record(result)
+			}
+			return
+		}
+		vulnerableCount := counts.vulnerable.Add(1)
+
+		if saveBodiesDir != "" {
+			if err := saveBody(saveBodiesDir, result); err != nil {
+				slog.Warn("failed to save response body", "subdomain", result.Subdomain, "error", err)
+			}
+		}
+
+		record(result)
+
+		if maxFindings > 0 && vulnerableCount >= int64(maxFindings) && ctx.Err() == nil {
+			slog.Info("maximum findings reached, stopping scan", "max_findings", maxFindings)
+			cancel()
+		}
+	}
+	if progress != nil {
+		// Interrupting the scan only loses the chunk in progress
+		err := scanChunks(ctx, s, scanInput, chunkSize, progress.Processed, emit, func(processed int) error {
+			if writeErr != nil {
+				return writeErr
+			}
+			if err := outFile.Sync(); err != nil {
+				return err
+			}
+			offset, err := outFile.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			progress.Processed, progress.Written, progress.Offset = processed, writer.Count(), offset
+			return progress.save(checkpointFile(outputFile))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to checkpoint scan: %w", err)
+		}
+	} else {
+		s.ScanStream(ctx, scanInput, emit)
+	}
+
+	// Excluded subdomains are reported once the input is exhausted
+	if excluder != nil {
+		if excludeMode == "mark" && ctx.Err() == nil {
+			for _, subdomain := range excluder.excluded {
+				emit(excludedResult(subdomain))
+			}
+		}
+		slog.Info("excluded subdomains", "excluded", len(excluder.excluded), "mode", excludeMode)
+	}
+
+	// Fresh baseline results are written as they were, without being
+	// scanned or counted again
+	carried := 0
+	if baseline != nil && ctx.Err() == nil {
+		for _, result := range baseline.fresh {
+			keep := outputAll || isFinding(result)
+			if onlyErrors {
+				keep = result.Status == "error"
+			}
+			if keep {
+				record(result)
+				carried++
+			}
+		}
+		slog.Info("carried over baseline results", "file", baselineFile, "fresh", len(baseline.fresh), "written", carried)
+	}
+
+	if status != nil {
+		status.stop()
+	}
+
+	if inputErr != nil {
+		return fmt.Errorf("failed to load subdomains from file: %w", inputErr)
+	}
+
+	// Identical pages served for many hosts are downgraded before anything
+	// is written
+	var clusters []scanner.Cluster
+	if trimClusterSize > 0 {
+		clusters = scanner.TrimClusters(findings, trimClusterSize)
+		for _, cluster := range clusters {
+			if cluster.Downgraded {
+				counts.vulnerable.Add(-int64(cluster.Size))
+			}
+		}
+	}
+
+	scannedCount, vulnerableCount := counts.scanned.Load(), counts.vulnerable.Load()
+	slog.Info("scan finished", "subdomains", scannedCount, "vulnerable", vulnerableCount, "slow", counts.slow.Load())
+
+	switch {
+	case sortBy != "":
+		output.SortResults(findings, sortBy)
+	case sortOutput == "alpha":
+		output.SortResults(findings, "subdomain")
+	case sortOutput == "input":
+		order.sort(findings)
+	case onlyErrors:
+		output.SortResults(findings, "error_type")
+	}
+
+	// Finish the output file if specified
+	if writer != nil {
+		if keepFindings {
+			for _, result := range findings {
+				if writeErr == nil {
+					writeErr = writer.Write(output.Project(result, fields))
+				}
+			}
+		}
+		if writeErr == nil {
+			writeErr = writer.Close()
+		}
+		if writeErr != nil {
+			return fmt.Errorf("failed to write output file: %w", writeErr)
+		}
+		slog.Info("results written", "file", outputFile, "vulnerable", vulnerableCount)
+
+		// The scan is complete, there is nothing left to resume
+		if progress != nil {
+			if err := os.Remove(checkpointFile(outputFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("failed to remove checkpoint", "error", err)
+			}
+		}
+	} else if outFile != nil && outputFormat == "html" {
+		if err := output.WriteHTML(outFile, findings); err != nil {
 			return fmt.Errorf("failed to write output file: %w", err)
 		}
-		if verbose {
-			fmt.Fprintf(os.Stderr, "\nResults written to %s (%d vulnerable subdomains)\n", outputFile, vulnerableCount)
+		slog.Info("results written", "file", outputFile, "format", outputFormat, "vulnerable", vulnerableCount)
+	} else if lines != nil {
+		if keepFindings {
+			for _, result := range findings {
+				// Downgraded findings are kept in JSON for their note
+				if result.ClusteredFalsePositive != "" && !outputAll {
+					continue
+				}
+				if writeErr == nil {
+					_, writeErr = fmt.Fprintln(lines, result.Subdomain)
+				}
+			}
+		}
+		if writeErr != nil {
+			return fmt.Errorf("failed to write output: %w", writeErr)
+		}
+		if outFile != nil {
+			slog.Info("results written", "file", outputFile, "format", outputFormat, "vulnerable", vulnerableCount)
+		}
+	}
+	if atomicOut != nil {
+		if err := atomicOut.Commit(); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+
+	if archive != nil {
+		if archiveErr == nil {
+			archiveErr = archive.Close()
+		}
+		if archiveErr == nil {
+			archiveErr = archiveOut.Commit()
+		}
+		if archiveErr != nil {
+			return fmt.Errorf("failed to write archive file: %w", archiveErr)
+		}
+		slog.Info("exchanges archived", "file", archiveFile)
+	}
+
+	if store != nil {
+		if keepFindings {
+			for _, result := range findings {
+				if storeErr == nil {
+					storeErr = store.Write(result)
+				}
+			}
+		}
+		if storeErr == nil {
+			storeErr = store.Finish()
+		}
+		if storeErr != nil {
+			return fmt.Errorf("failed to write SQLite database: %w", storeErr)
+		}
+		slog.Info("results stored", "file", sqliteFile, "run_id", store.RunID())
+	}
+
+	if capper != nil && capper.err != nil {
+		return capper.err
+	}
+	if aborter != nil && aborter.err != nil {
+		return aborter.err
+	}
+	if plainText {
+		return nil
+	}
+
+	if groupBy == "service" && !quiet && !onlyErrors {
+		output.PrintGroupedByService(findings)
+	}
+	if onlyErrors && !quiet {
+		output.PrintGroupedByErrorType(findings)
+	}
+	if timingBreakdown && !quiet {
+		timings.print(os.Stdout)
+	}
+	if len(clusters) > 0 && !quiet {
+		printClusters(clusters, trimClusterSize)
+	}
+
+	// Slow responses, clustered findings and carried over results are only
+	// counted with --slow-threshold, --trim-cdn-wildcards and --baseline
+	extraCounts := ""
+	if slowThreshold > 0 {
+		extraCounts = fmt.Sprintf(", %d slow", counts.slow.Load())
+	}
+	if trimClusterSize > 0 {
+		extraCounts += fmt.Sprintf(", %d clustered", downgradedCount(clusters))
+	}
+	if baseline != nil {
+		extraCounts += fmt.Sprintf(", %d carried over", carried)
+	}
+
+	if quiet && onlyErrors {
+		fmt.Printf("%d errors / %d scanned%s\n", counts.errored.Load(), scannedCount, extraCounts)
+	} else if quiet {
+		if smp != nil {
+			fmt.Printf("%d vulnerable / %d scanned%s (%s)\n", vulnerableCount, scannedCount, extraCounts, smp)
+		} else {
+			fmt.Printf("%d vulnerable / %d scanned%s\n", vulnerableCount, scannedCount, extraCounts)
+		}
+	} else {
+		if slowThreshold > 0 {
+			fmt.Printf("\nSlow subdomains: %d (a response took over %s)\n", counts.slow.Load(), slowThreshold)
+		}
+		if baseline != nil {
+			fmt.Printf("\nCarried over from %s: %d results scanned within %s, not scanned again\n", baselineFile, carried, since)
+		}
+		if smp != nil {
+			fmt.Printf("\nResults are based on a %s\n", smp)
 		}
 	}
 
 	return nil
 }
 
-func loadSubdomainsFromFile(filename string) ([]string, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
+// readSubdomains sends every subdomain read from r (one per line, blank
+// lines and # comments ignored) to the channel. Trailing "#key=value" tags
+// are recorded in tags.
+func readSubdomains(r io.Reader, tags *inputTags, subdomains chan<- string) error {
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		subdomain, lineTags := splitTags(line)
+		if lineTags != nil {
+			tags.set(subdomain, lineTags)
+		}
+		subdomains <- subdomain
 	}
 
-	lines := strings.Split(string(data), "\n")
-	var subdomains []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			subdomains = append(subdomains, line)
+	return lines.Err()
+}
+
+// readPairs reads "ip,hostname" lines, pins each hostname to its IP address
+// and sends the hostname to the channel. Blank lines and # comments are
+// ignored, trailing "#key=value" tags are recorded in tags.
+func readPairs(r io.Reader, pins *dns.Pins, tags *inputTags, subdomains chan<- string) error {
+	lines := bufio.NewScanner(r)
+	number := 0
+	for lines.Scan() {
+		number++
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pair, lineTags := splitTags(line)
+		ip, hostname, ok := strings.Cut(pair, ",")
+		ip, hostname = strings.TrimSpace(ip), strings.TrimSpace(hostname)
+		if !ok || net.ParseIP(ip) == nil || hostname == "" {
+			return fmt.Errorf("line %d: expected ip,hostname, got %q", number, line)
+		}
+
+		pins.Set(hostname, ip)
+		if lineTags != nil {
+			tags.set(hostname, lineTags)
 		}
+		subdomains <- hostname
+	}
+
+	return lines.Err()
+}
+
+// readCSVSubdomains sends the subdomain column of every row of a CSV file to
+// the channel. The first row is the header; column is a header name or a
+// 0-based index, empty for the first column.
+func readCSVSubdomains(r io.Reader, column string, subdomains chan<- string) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	index, err := csvColumnIndex(header, column)
+	if err != nil {
+		return err
 	}
 
-	return subdomains, nil
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if index >= len(record) {
+			continue
+		}
+		subdomain := strings.TrimSpace(record[index])
+		if subdomain != "" && !strings.HasPrefix(subdomain, "#") {
+			subdomains <- subdomain
+		}
+	}
 }
 
-func outputToFile(results []types.Result, filename string) error {
-	// Filter only vulnerable results
-	vulnerableResults := make([]types.Result, 0)
-	for _, result := range results {
-		if result.Vulnerable && result.Status == "vulnerable" {
-			vulnerableResults = append(vulnerableResults, result)
+// csvColumnIndex resolves a column given by header name or 0-based index
+func csvColumnIndex(header []string, column string) (int, error) {
+	if column == "" {
+		return 0, nil
+	}
+
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
 		}
 	}
 
+	if index, err := strconv.Atoi(column); err == nil && index >= 0 && index < len(header) {
+		return index, nil
+	}
+
+	return 0, fmt.Errorf("column %q not found in CSV header", column)
+}
+
+// saveBody writes the untruncated body of the response the verdict was based
+// on to a file named after the host
+func saveBody(dir string, result types.Result) error {
+	resp := result.CheckedResponse()
+	if resp == nil || resp.FullBody == nil {
+		return nil
+	}
+
+	name := strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(result.Subdomain) + ".body"
+	return os.WriteFile(filepath.Join(dir, name), resp.FullBody, 0644)
+}
+
+// newResultsWriter creates the writer for a JSON results file: a versioned
+// document, or a bare array with --legacy-output
+func newResultsWriter(w io.Writer, started time.Time) (*output.JSONArrayWriter, error) {
+	if legacyOutput {
+		return output.NewJSONArrayWriter(w), nil
+	}
+	return output.NewDocumentWriter(w, output.DocumentHeader{
+		SchemaVersion: output.SchemaVersion,
+		ToolVersion:   buildVersion,
+		ScanStartedAt: started,
+	})
+}
+
+// createOutputFile creates the output file along with its directory
+func createOutputFile(filename string) (*os.File, error) {
 	// Ensure directory exists
 	dir := filepath.Dir(filename)
 	if dir != "." {
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(vulnerableResults)
+	return os.Create(filename)
 }
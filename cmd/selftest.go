@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"subtake/internal/config"
+	"subtake/internal/fingerprints"
+	"subtake/internal/output"
+	"subtake/internal/selftest"
+
+	"github.com/spf13/cobra"
+)
+
+// selftestCmd represents the selftest command
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Verify every fingerprint matches its canonical body",
+	Long: `Selftest serves the canonical body of every fingerprint from a local
+server, scans it and checks that each fingerprint fires exactly once on its
+own body. No traffic leaves the machine.
+
+Use --fingerprints to include a custom fingerprints file in the test.`,
+	Args: cobra.NoArgs,
+	RunE: runSelftest,
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+
+	selftestCmd.Flags().StringVar(&fingerprintsFile, "fingerprints", "", "custom fingerprints file (JSON/YAML)")
+	selftestCmd.Flags().StringVar(&importFormat, "import-format", fingerprints.FormatSubtake, "format of the custom fingerprints file: subtake, or canitakeover for a can-i-take-over-xyz fingerprints.json")
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	showBanner()
+
+	// Load fingerprints
+	fp, err := fingerprints.Load(fingerprintsFile, importFormat)
+	if err != nil {
+		return fmt.Errorf("failed to load fingerprints: %w", err)
+	}
+
+	cfg := &config.Config{
+		UserAgent: "SubTake/selftest",
+		Timeout:   5 * time.Second,
+		Threshold: fingerprints.DefaultThreshold,
+	}
+
+	results, err := selftest.Run(cfg, fp)
+	if err != nil {
+		return fmt.Errorf("failed to run self-test: %w", err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Passed() {
+			continue
+		}
+		failed++
+
+		reason := fmt.Sprintf("fired %d times", result.Matches)
+		if result.Error != "" {
+			reason = result.Error
+		} else if result.Fingerprint.CanonicalBody() == "" {
+			reason = "no example body for regex fingerprint"
+		}
+		fmt.Printf("%s[FAIL]%s %s %q - %s\n", output.ColorRed, output.ColorReset,
+			result.Fingerprint.Service, result.Fingerprint.Pattern, reason)
+	}
+
+	fmt.Printf("%d/%d fingerprints matched their canonical body\n", len(results)-failed, len(results))
+
+	if failed > 0 {
+		return fmt.Errorf("%d fingerprints failed the self-test", failed)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"subtake/internal/fingerprints"
+
+	"github.com/spf13/cobra"
+)
+
+// servicesCmd represents the services command
+var servicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "List the services subtake can detect",
+	Long: `Services loads the default fingerprints merged with the custom file, if
+any, and prints every distinct service along with the number of fingerprints
+detecting it, to review the coverage before a scan. Disabled fingerprints are
+not counted.`,
+	Args: cobra.NoArgs,
+	RunE: runServices,
+}
+
+func init() {
+	rootCmd.AddCommand(servicesCmd)
+
+	servicesCmd.Flags().StringVar(&fingerprintsFile, "fingerprints", "", "custom fingerprints file (JSON/YAML)")
+	servicesCmd.Flags().StringVar(&importFormat, "import-format", fingerprints.FormatSubtake, "format of the custom fingerprints file: subtake, or canitakeover for a can-i-take-over-xyz fingerprints.json")
+}
+
+func runServices(cmd *cobra.Command, args []string) error {
+	fp, err := fingerprints.Load(fingerprintsFile, importFormat)
+	if err != nil {
+		return fmt.Errorf("failed to load fingerprints: %w", err)
+	}
+
+	// Disabled fingerprints detect nothing
+	fp = fp.Enabled()
+	counts := make(map[string]int)
+	for _, fingerprint := range fp.Fingerprints {
+		counts[fingerprint.Service]++
+	}
+
+	services := make([]string, 0, len(counts))
+	for service := range counts {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tFINGERPRINTS")
+	for _, service := range services {
+		fmt.Fprintf(w, "%s\t%d\n", service, counts[service])
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d services, %d fingerprints\n", len(services), len(fp.Fingerprints))
+	return nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statusInterval is how often the live status line is redrawn
+const statusInterval = 500 * time.Millisecond
+
+// tally counts scanned and vulnerable subdomains as results arrive. The
+// counters are safe to read while the scan updates them.
+type tally struct {
+	scanned    atomic.Int64
+	vulnerable atomic.Int64
+	errored    atomic.Int64
+	slow       atomic.Int64
+}
+
+// String formats the tally as "[42 vulnerable / 9,310 scanned]"
+func (t *tally) String() string {
+	return fmt.Sprintf("[%s vulnerable / %s scanned]", groupDigits(t.vulnerable.Load()), groupDigits(t.scanned.Load()))
+}
+
+// statusLine redraws the tally on a single terminal line until stopped.
+// Output written to the same terminal has to go through print so the status
+// line does not get mixed into it.
+type statusLine struct {
+	w     io.Writer
+	tally *tally
+	mu    sync.Mutex
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// startStatusLine starts redrawing the tally on w
+func startStatusLine(w io.Writer, t *tally) *statusLine {
+	s := &statusLine{w: w, tally: t, done: make(chan struct{})}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(statusInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.draw()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return s
+}
+
+// draw prints the current tally over the previous one
+func (s *statusLine) draw() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "\r\033[K%s", s.tally)
+}
+
+// clear erases the status line; it is drawn again on the next tick
+func (s *statusLine) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprint(s.w, "\r\033[K")
+}
+
+// print erases the status line and calls write to print output in its
+// place, holding off redraws until write returns
+func (s *statusLine) print(write func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprint(s.w, "\r\033[K")
+	write()
+}
+
+// stop stops redrawing and erases the status line
+func (s *statusLine) stop() {
+	close(s.done)
+	s.wg.Wait()
+	s.clear()
+}
+
+// groupDigits formats n with thousands separators
+func groupDigits(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	if n < 0 {
+		return "-" + groupDigits(-n)
+	}
+
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
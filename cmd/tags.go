@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"sync"
+)
+
+// inputTags maps subdomains to the tags given with them in the input, e.g.
+// "sub.example.com #team=payments #env=prod". Tags can be added while they
+// are being looked up.
+type inputTags struct {
+	mu   sync.RWMutex
+	tags map[string]map[string]string
+}
+
+func newInputTags() *inputTags {
+	return &inputTags{tags: make(map[string]map[string]string)}
+}
+
+// set records the tags of a subdomain
+func (t *inputTags) set(subdomain string, tags map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tags[strings.ToLower(subdomain)] = tags
+}
+
+// get returns the tags of a subdomain, or nil when it has none
+func (t *inputTags) get(subdomain string) map[string]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tags[strings.ToLower(subdomain)]
+}
+
+// splitTags separates the trailing tags from an input line. Tags are
+// "#key=value" tokens following the entry after whitespace; any other text
+// after a " #" is a comment. Lines without " #" are returned unchanged.
+func splitTags(line string) (string, map[string]string) {
+	index := strings.IndexAny(line, " \t")
+	if index < 0 || !strings.Contains(line[index:], "#") {
+		return line, nil
+	}
+
+	hash := strings.Index(line[index:], "#") + index
+	if strings.TrimSpace(line[index:hash]) != "" {
+		// Whitespace inside the entry itself, not before a tag
+		return line, nil
+	}
+
+	var tags map[string]string
+	for _, token := range strings.Fields(line[hash:]) {
+		key, value, ok := strings.Cut(strings.TrimPrefix(token, "#"), "=")
+		if !strings.HasPrefix(token, "#") || !ok || key == "" {
+			break
+		}
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[key] = value
+	}
+	return line[:index], tags
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"subtake/internal/types"
+)
+
+// timingStats collects the phase durations of the requests of a scan to
+// report their percentiles
+type timingStats struct {
+	requests  int
+	dns       []float64
+	connect   []float64
+	tls       []float64
+	firstByte []float64
+}
+
+// add records the timing of every response of the result. Phases that did
+// not happen are left out, so reused connections do not skew the DNS and
+// connect percentiles towards zero.
+func (t *timingStats) add(result types.Result) {
+	for _, resp := range result.Responses() {
+		if resp.Timing == nil {
+			continue
+		}
+		t.requests++
+		for _, phase := range []struct {
+			value   float64
+			samples *[]float64
+		}{
+			{resp.Timing.DNS, &t.dns},
+			{resp.Timing.Connect, &t.connect},
+			{resp.Timing.TLS, &t.tls},
+			{resp.Timing.FirstByte, &t.firstByte},
+		} {
+			if phase.value > 0 {
+				*phase.samples = append(*phase.samples, phase.value)
+			}
+		}
+	}
+}
+
+// print writes the p50, p90 and p99 of every phase
+func (t *timingStats) print(w io.Writer) {
+	if t.requests == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nTiming over %d requests (p50 / p90 / p99):\n", t.requests)
+	for _, phase := range []struct {
+		name    string
+		samples []float64
+	}{
+		{"DNS", t.dns},
+		{"Connect", t.connect},
+		{"TLS", t.tls},
+		{"First byte", t.firstByte},
+	} {
+		if len(phase.samples) == 0 {
+			fmt.Fprintf(w, "  %-11s -\n", phase.name)
+			continue
+		}
+		sort.Float64s(phase.samples)
+		fmt.Fprintf(w, "  %-11s %s / %s / %s\n", phase.name, formatMillis(percentile(phase.samples, 50)),
+			formatMillis(percentile(phase.samples, 90)), formatMillis(percentile(phase.samples, 99)))
+	}
+}
+
+// percentile returns the p-th percentile of sorted samples by nearest rank
+func percentile(sorted []float64, p int) float64 {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
+
+// formatMillis formats a duration given in milliseconds
+func formatMillis(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Millisecond / 10).String()
+}
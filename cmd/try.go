@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"subtake/internal/fingerprints"
+	"subtake/internal/httpclient"
+	"subtake/internal/scanner"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tryURL     string
+	tryPattern string
+	tryRegex   bool
+)
+
+// tryCmd represents the try command
+var tryCmd = &cobra.Command{
+	Use:   "try --url <url> --pattern <pattern>",
+	Short: "Test a candidate fingerprint pattern against a live URL",
+	Long: `Try fetches the URL with the regular HTTP client and reports whether the
+pattern matches the response body, printing the surrounding text with the
+match highlighted. Use it to iterate on a fingerprint before adding it to a
+fingerprints file.`,
+	Args: cobra.NoArgs,
+	RunE: runTry,
+}
+
+func init() {
+	rootCmd.AddCommand(tryCmd)
+
+	tryCmd.Flags().StringVar(&tryURL, "url", "", "URL to fetch (required)")
+	tryCmd.Flags().StringVar(&tryPattern, "pattern", "", "pattern to test against the response body (required)")
+	tryCmd.Flags().BoolVar(&tryRegex, "regex", false, "treat the pattern as a regular expression")
+	tryCmd.MarkFlagRequired("url")
+	tryCmd.MarkFlagRequired("pattern")
+	addProbeFlags(tryCmd)
+}
+
+func runTry(cmd *cobra.Command, args []string) error {
+	url := tryURL
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+
+	fingerprint := fingerprints.Fingerprint{Pattern: tryPattern, Regex: tryRegex}
+
+	client, err := httpclient.New(buildConfig())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	resp := client.Get(context.Background(), url)
+	if resp.Error != nil {
+		return fmt.Errorf("failed to fetch %s: %w", url, resp.Error)
+	}
+
+	fmt.Printf("Fetched %s (status %d, %d bytes)\n", url, resp.StatusCode, len(resp.Body))
+
+	// Like in scans, binary bodies are not matched against
+	if scanner.IsBinary(resp.Headers.Get("Content-Type"), resp.Body) {
+		fmt.Println("\033[31mNo match\033[0m: the body is binary and is not matched against")
+		return nil
+	}
+
+	body := resp.Body
+	match, err := fingerprint.Find(body)
+	if err != nil {
+		return err
+	}
+
+	// Fingerprints also match the entity-decoded body
+	decoded := false
+	if unescaped := html.UnescapeString(body); match == nil && unescaped != body {
+		body, decoded = unescaped, true
+		match, _ = fingerprint.Find(body)
+	}
+	if match == nil {
+		fmt.Println("\033[31mNo match\033[0m")
+		return nil
+	}
+
+	if decoded {
+		fmt.Printf("\033[32mMatch\033[0m at offset %d of the HTML entity-decoded body\n\n", match[0])
+	} else {
+		fmt.Printf("\033[32mMatch\033[0m at offset %d\n\n", match[0])
+	}
+	fmt.Println(highlightMatch(body, match[0], match[1], 100))
+	return nil
+}
+
+// highlightMatch returns the match with up to context bytes around it, the
+// match itself in bold red
+func highlightMatch(body string, start, end, context int) string {
+	from := max(start-context, 0)
+	to := min(end+context, len(body))
+
+	return body[from:start] + "\033[1;31m" + body[start:end] + "\033[0m" + body[end:to]
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"subtake/internal/fingerprints"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// maxBundleSize bounds the download of a fingerprint bundle and its signature
+const maxBundleSize = 10 << 20
+
+// updateTimeout bounds each download of the update
+const updateTimeout = time.Minute
+
+// updateSettings are the defaults of `fingerprints update`, read from
+// update.yaml in the subtake user config directory so the public key stays
+// pinned across runs
+type updateSettings struct {
+	URL          string `yaml:"url"`
+	SignatureURL string `yaml:"signature_url"`
+	PublicKey    string `yaml:"public_key"`
+	SHA256       string `yaml:"sha256"`
+}
+
+var (
+	updateURL          string
+	updateSignatureURL string
+	updatePublicKey    string
+	updateSHA256       string
+)
+
+// fingerprintUpdateCmd represents the fingerprints update command
+var fingerprintUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Download and verify a remote fingerprint bundle",
+	Long: `Update downloads a fingerprint bundle in the subtake format, verifies it
+against a pinned minisign public key or SHA-256 checksum and caches it in the
+user cache directory. Later scans load the cached bundle along with the
+built-in fingerprints. A bundle that fails verification is never cached.
+
+The URL, signature URL, public key and checksum default to the url,
+signature_url, public_key and sha256 keys of update.yaml in the subtake user
+config directory, e.g. ~/.config/subtake/update.yaml.`,
+	Args: cobra.NoArgs,
+	RunE: runFingerprintUpdate,
+}
+
+func init() {
+	fingerprintsCmd.AddCommand(fingerprintUpdateCmd)
+
+	fingerprintUpdateCmd.Flags().StringVar(&updateURL, "url", "", "URL of the fingerprint bundle (JSON/YAML)")
+	fingerprintUpdateCmd.Flags().StringVar(&updateSignatureURL, "signature-url", "", "URL of the minisign signature of the bundle (default the bundle URL with .minisig appended)")
+	fingerprintUpdateCmd.Flags().StringVar(&updatePublicKey, "public-key", "", "minisign public key the bundle must be signed with")
+	fingerprintUpdateCmd.Flags().StringVar(&updateSHA256, "sha256", "", "expected SHA-256 checksum of the bundle, instead of a signature")
+}
+
+// loadUpdateSettings reads update.yaml from the user config directory. A
+// missing file leaves every setting empty.
+func loadUpdateSettings() (*updateSettings, error) {
+	settings := &updateSettings{}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return settings, nil
+	}
+	filename := filepath.Join(dir, "subtake", "update.yaml")
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	slog.Debug("loaded update settings", "file", filename)
+	return settings, nil
+}
+
+func runFingerprintUpdate(cmd *cobra.Command, args []string) error {
+	settings, err := loadUpdateSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load update settings: %w", err)
+	}
+
+	// Flags win over the settings file
+	for _, setting := range []struct {
+		flag  string
+		value string
+		dest  *string
+	}{
+		{"url", updateURL, &settings.URL},
+		{"signature-url", updateSignatureURL, &settings.SignatureURL},
+		{"public-key", updatePublicKey, &settings.PublicKey},
+		{"sha256", updateSHA256, &settings.SHA256},
+	} {
+		if cmd.Flags().Changed(setting.flag) {
+			*setting.dest = setting.value
+		}
+	}
+
+	if settings.URL == "" {
+		return fmt.Errorf("no bundle URL: use --url or set url in update.yaml")
+	}
+	if settings.PublicKey == "" && settings.SHA256 == "" {
+		return fmt.Errorf("refusing to install an unverified bundle: use --public-key or --sha256, or set public_key or sha256 in update.yaml")
+	}
+
+	client := &http.Client{Timeout: updateTimeout}
+	bundle, err := download(client, settings.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download bundle: %w", err)
+	}
+
+	if settings.PublicKey != "" {
+		signatureURL := settings.SignatureURL
+		if signatureURL == "" {
+			signatureURL = settings.URL + ".minisig"
+		}
+		signature, err := download(client, signatureURL)
+		if err != nil {
+			return fmt.Errorf("failed to download signature: %w", err)
+		}
+		if err := fingerprints.VerifyMinisign(bundle, signature, settings.PublicKey); err != nil {
+			return fmt.Errorf("bundle from %s rejected: %w", settings.URL, err)
+		}
+	}
+	if settings.SHA256 != "" {
+		if err := fingerprints.VerifySHA256(bundle, settings.SHA256); err != nil {
+			return fmt.Errorf("bundle from %s rejected: %w", settings.URL, err)
+		}
+	}
+
+	fp, filename, err := fingerprints.SaveBundle(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to install bundle: %w", err)
+	}
+
+	fmt.Printf("Installed %d fingerprints from %s to %s\n", len(fp.Fingerprints), settings.URL, filename)
+	return nil
+}
+
+// download fetches url, failing on a non-200 status or a body larger than
+// maxBundleSize
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBundleSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBundleSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, maxBundleSize)
+	}
+	return data, nil
+}
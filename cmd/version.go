@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version information",
+	Long: `Version prints the build version, commit and Go version
+subtake was built with.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("subtake %s\n", versionString())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"net"
+	"strings"
+)
+
+// wwwPrefix is the prefix of the variant added by --probe-www
+const wwwPrefix = "www."
+
+// wwwVariant returns the www-prefixed variant of a subdomain, or "" when it
+// already has the prefix or is not a host name, e.g. an IP address or an
+// entry given as a URL
+func wwwVariant(subdomain string) string {
+	host := subdomain
+	if h, _, err := net.SplitHostPort(subdomain); err == nil {
+		host = h
+	}
+	if strings.HasPrefix(strings.ToLower(host), wwwPrefix) || strings.Contains(host, "/") || net.ParseIP(host) != nil {
+		return ""
+	}
+	return wwwPrefix + subdomain
+}
+
+// probeWWW forwards every subdomain of in followed by its www-prefixed
+// variant. Subdomains are forwarded once, so a list that already holds both
+// variants is not scanned twice. added is called with each variant and the
+// subdomain it was derived from before it is forwarded.
+func probeWWW(in <-chan string, out chan<- string, added func(variant, subdomain string)) {
+	defer close(out)
+
+	seen := make(map[string]bool)
+	forward := func(subdomain string) bool {
+		key := strings.ToLower(subdomain)
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		out <- subdomain
+		return true
+	}
+
+	for subdomain := range in {
+		forward(subdomain)
+		if variant := wwwVariant(subdomain); variant != "" && !seen[strings.ToLower(variant)] {
+			added(variant, subdomain)
+			forward(variant)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache stores JSON encoded values on disk, one file per key, and expires
+// them after a TTL
+type Cache struct {
+	dir     string
+	ttl     time.Duration
+	refresh bool
+}
+
+// New creates a cache in dir. With refresh set, lookups always miss so every
+// value is fetched and stored again.
+func New(dir string, ttl time.Duration, refresh bool) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Cache{dir: dir, ttl: ttl, refresh: refresh}, nil
+}
+
+// Get decodes the cached value for key into v and reports whether a fresh
+// entry was found
+func (c *Cache) Get(key string, v interface{}) bool {
+	if c.refresh {
+		return false
+	}
+
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		return false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(data, v) == nil
+}
+
+// Put stores v under key
+func (c *Cache) Put(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see partial entries
+	tmp, err := os.CreateTemp(c.dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
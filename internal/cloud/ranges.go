@@ -0,0 +1,130 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Range is an address block of a cloud provider from which customers are
+// assigned addresses, e.g. AWS Elastic IPs
+type Range struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Prefix   string `json:"prefix" yaml:"prefix"`
+
+	prefix netip.Prefix
+}
+
+// Ranges holds the known provider ranges
+type Ranges struct {
+	Ranges []Range `json:"ranges" yaml:"ranges"`
+}
+
+// Load returns the bundled ranges followed by those of the custom file, if
+// any
+func Load(customFile string) (*Ranges, error) {
+	ranges := Default()
+	if customFile != "" {
+		custom, err := loadFromFile(customFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load custom cloud ranges: %w", err)
+		}
+		ranges.Ranges = append(ranges.Ranges, custom.Ranges...)
+	}
+
+	for i := range ranges.Ranges {
+		r := &ranges.Ranges[i]
+		prefix, err := netip.ParsePrefix(r.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q of %s: %w", r.Prefix, r.Provider, err)
+		}
+		r.prefix = prefix.Masked()
+	}
+	return ranges, nil
+}
+
+func loadFromFile(filename string) (*Ranges, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges Ranges
+
+	// Try JSON first, then YAML
+	if strings.HasSuffix(strings.ToLower(filename), ".json") {
+		err = json.Unmarshal(data, &ranges)
+	} else {
+		err = yaml.Unmarshal(data, &ranges)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cloud ranges file: %w", err)
+	}
+
+	for _, r := range ranges.Ranges {
+		if r.Provider == "" {
+			return nil, fmt.Errorf("range %q without a provider in %s", r.Prefix, filename)
+		}
+	}
+
+	return &ranges, nil
+}
+
+// Match returns the most specific range containing the address, or nil
+func (r *Ranges) Match(addr netip.Addr) *Range {
+	addr = addr.Unmap()
+
+	var best *Range
+	for i := range r.Ranges {
+		candidate := &r.Ranges[i]
+		if !candidate.prefix.Contains(addr) {
+			continue
+		}
+		if best == nil || candidate.prefix.Bits() > best.prefix.Bits() {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// Default returns the bundled ranges: an excerpt of the compute address
+// blocks published by AWS (ip-ranges.json), Google Cloud (cloud.json) and
+// Azure (service tags). Load the full, current lists with a custom file.
+func Default() *Ranges {
+	return &Ranges{
+		Ranges: []Range{
+			// AWS
+			{Provider: "AWS", Prefix: "3.0.0.0/8"},
+			{Provider: "AWS", Prefix: "18.128.0.0/9"},
+			{Provider: "AWS", Prefix: "52.0.0.0/11"},
+			{Provider: "AWS", Prefix: "54.144.0.0/12"},
+			{Provider: "AWS", Prefix: "54.160.0.0/12"},
+			{Provider: "AWS", Prefix: "2600:1f00::/24"},
+
+			// Google Cloud
+			{Provider: "Google Cloud", Prefix: "34.64.0.0/10"},
+			{Provider: "Google Cloud", Prefix: "35.184.0.0/13"},
+			{Provider: "Google Cloud", Prefix: "35.192.0.0/14"},
+			{Provider: "Google Cloud", Prefix: "35.224.0.0/12"},
+			{Provider: "Google Cloud", Prefix: "104.154.0.0/15"},
+			{Provider: "Google Cloud", Prefix: "104.196.0.0/14"},
+			{Provider: "Google Cloud", Prefix: "146.148.0.0/17"},
+			{Provider: "Google Cloud", Prefix: "2600:1900::/28"},
+
+			// Azure
+			{Provider: "Azure", Prefix: "13.64.0.0/11"},
+			{Provider: "Azure", Prefix: "20.40.0.0/13"},
+			{Provider: "Azure", Prefix: "52.160.0.0/11"},
+			{Provider: "Azure", Prefix: "52.224.0.0/11"},
+			{Provider: "Azure", Prefix: "104.40.0.0/13"},
+			{Provider: "Azure", Prefix: "137.116.0.0/15"},
+			{Provider: "Azure", Prefix: "168.61.0.0/16"},
+			{Provider: "Azure", Prefix: "168.62.0.0/15"},
+		},
+	}
+}
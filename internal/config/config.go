@@ -1,13 +1,80 @@
 package config
 
-import "time"
+import (
+	"time"
+
+	"subtake/internal/dns"
+)
 
 // Config holds the configuration for the scanner
 type Config struct {
-	UserAgent      string
-	Insecure       bool
-	Rate           int
-	TimeoutRetries int
-	Timeout        time.Duration
-	Verbose        bool
+	UserAgent       string
+	Insecure        bool
+	Rate            int
+	Delay           time.Duration
+	TimeoutRetries  int
+	Timeout         time.Duration
+	HostTimeout     time.Duration
+	MaxBackoff      time.Duration
+	Threshold       int
+	CoalesceByCNAME bool
+	KeepFullBody    bool
+	ActiveConfirm   bool
+	AllowPost       bool // send the requests of fingerprints that define one
+	MatchRaw        bool // match fingerprints against the bytes of malformed responses
+	Ordered         bool // stream results in input order rather than as they complete
+	MatcherCmd      string
+	CacheDir        string
+	CacheTTL        time.Duration
+	CacheRefresh    bool
+	Ports           []int
+	UserAgentsFile  string
+	TLSMinVersion   string
+	TLSMaxVersion   string
+	Passive         bool
+	MaxCNAMEDepth   int
+	Cookies         []string
+	BearerToken     string
+	DiffBodies      bool
+	RatePerHost     int
+	Pins            *dns.Pins
+	ServicesFile    string
+	SnippetWindow   int
+	AllMatches      bool
+	NoKeepAlive     bool
+	MaxIdlePerHost  int
+	Verify          bool
+	RetryEmpty      bool
+	CompareIP       bool
+	CloudRangesFile string
+	HTTPFallback    bool
+	Accept          string
+	Headers         []string
+	FirstMatch      bool
+	MatchPolicy     string // all, per-service or first
+	Timing          bool
+	BinaryBodies    string // hex, base64 or omit
+	SlowThreshold   time.Duration
+	MaxConnections  int // requests in flight at once across all workers (0 = no limit)
+
+	// ProbeConcurrency is the number of protocols and ports of a subdomain
+	// probed at once (0 or 1 = one after the other)
+	ProbeConcurrency int
+
+	// A 200 response with a body of at least LegitMinBytes and LegitMinLinks
+	// links is a live site that needs strong evidence to be flagged
+	// (LegitMinBytes 0 = off)
+	LegitMinBytes int
+	LegitMinLinks int
+
+	// Circuit breaker per apex: consecutive failures before the remaining
+	// subdomains are skipped (0 = off), and the wait before probing again
+	// (0 = never)
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// Redaction of response bodies before they are stored: the built-in
+	// secret patterns plus any custom ones, which imply Redact
+	Redact         bool
+	RedactPatterns []string
 }
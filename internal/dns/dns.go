@@ -0,0 +1,76 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxCNAMEDepth is the default number of CNAME hops followed
+const DefaultMaxCNAMEDepth = 10
+
+// Errors returned with a partial chain for pathological zones
+var (
+	ErrCNAMELoop  = errors.New("CNAME loop")
+	ErrCNAMEDepth = errors.New("CNAME chain too deep")
+)
+
+// DNS anomalies recorded on results
+const (
+	AnomalyCNAMELoop  = "cname_loop"
+	AnomalyCNAMEDepth = "cname_depth_exceeded"
+)
+
+// Anomaly returns the anomaly name for a ResolveCNAME error, or "" when the
+// error is not an anomaly of the zone
+func Anomaly(err error) string {
+	switch {
+	case errors.Is(err, ErrCNAMELoop):
+		return AnomalyCNAMELoop
+	case errors.Is(err, ErrCNAMEDepth):
+		return AnomalyCNAMEDepth
+	}
+	return ""
+}
+
+// ResolveCNAME returns the CNAME chain for the given host, excluding the host
+// itself. An empty chain means the host has no CNAME record. Each hop is
+// queried separately, so dangling chains are returned in full. At most
+// maxDepth hops are followed; a loop or a deeper chain returns the hops seen
+// so far along with ErrCNAMELoop or ErrCNAMEDepth.
+func ResolveCNAME(ctx context.Context, host string, maxDepth int) ([]string, error) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxCNAMEDepth
+	}
+
+	var chain []string
+	seen := map[string]bool{normalize(host): true}
+	current := host
+
+	for {
+		target, err := QueryCNAME(ctx, current)
+		if err != nil {
+			return chain, err
+		}
+		if target == "" {
+			return chain, nil
+		}
+
+		if seen[normalize(target)] {
+			return append(chain, target), fmt.Errorf("%w: %s points back to %s", ErrCNAMELoop, current, target)
+		}
+		if len(chain) == maxDepth {
+			return chain, fmt.Errorf("%w: more than %d hops", ErrCNAMEDepth, maxDepth)
+		}
+
+		seen[normalize(target)] = true
+		chain = append(chain, target)
+		current = target
+	}
+}
+
+// normalize lowercases a name and strips the trailing dot
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
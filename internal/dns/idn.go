@@ -0,0 +1,69 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// idnaProfile converts internationalized host names like a resolver does,
+// but accepts labels such as "_dmarc" that are not valid host names
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
+
+// ToASCII converts an internationalized host, optionally with a port, to the
+// punycode form used in DNS and HTTP, e.g. "münchen.example.com" to
+// "xn--mnchen-3ya.example.com". ASCII hosts are returned unchanged.
+func ToASCII(host string) (string, error) {
+	if isASCII(host) {
+		return host, nil
+	}
+	if !utf8.ValidString(host) {
+		return "", errors.New("invalid UTF-8")
+	}
+	name, port := splitPort(host)
+	ascii, err := idnaProfile.ToASCII(name)
+	if err != nil {
+		return "", err
+	}
+	return joinPort(ascii, port), nil
+}
+
+// ToUnicode returns the Unicode form of a host with punycode labels,
+// optionally with a port, or "" when it has none or they do not decode
+func ToUnicode(host string) string {
+	if !strings.Contains(strings.ToLower(host), "xn--") {
+		return ""
+	}
+	name, port := splitPort(host)
+	unicode, err := idnaProfile.ToUnicode(name)
+	if err != nil || unicode == name {
+		return ""
+	}
+	return joinPort(unicode, port)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPort(host string) (string, string) {
+	if name, port, err := net.SplitHostPort(host); err == nil {
+		return name, port
+	}
+	return host, ""
+}
+
+func joinPort(name, port string) string {
+	if port == "" {
+		return name
+	}
+	return net.JoinHostPort(name, port)
+}
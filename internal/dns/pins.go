@@ -0,0 +1,38 @@
+package dns
+
+import (
+	"strings"
+	"sync"
+)
+
+// Pins maps hostnames to the IP address to connect to instead of resolving
+// them, e.g. to test an environment before its DNS cutover. Pins can be added
+// while they are being looked up.
+type Pins struct {
+	mu  sync.RWMutex
+	ips map[string]string
+}
+
+// NewPins creates an empty set of pins
+func NewPins() *Pins {
+	return &Pins{ips: make(map[string]string)}
+}
+
+// Set pins host to ip
+func (p *Pins) Set(host, ip string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ips[normalize(host)] = ip
+}
+
+// Lookup returns the IP address host is pinned to
+func (p *Pins) Lookup(host string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	ip, ok := p.ips[normalize(strings.TrimSpace(host))]
+	return ip, ok
+}
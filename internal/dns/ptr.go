@@ -0,0 +1,54 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// LookupPTR returns the host names the PTR records of addr point at. An
+// address without PTR records returns no names and no error.
+func LookupPTR(ctx context.Context, addr netip.Addr) ([]string, error) {
+	if useSystemResolver {
+		return systemLookupPTR(ctx, addr)
+	}
+
+	msg, err := query(ctx, reverseName(addr), dnsmessage.TypePTR)
+	if errors.Is(err, ErrNXDOMAIN) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, answer := range msg.Answers {
+		if ptr, ok := answer.Body.(*dnsmessage.PTRResource); ok {
+			names = append(names, strings.ToLower(strings.TrimSuffix(ptr.PTR.String(), ".")))
+		}
+	}
+	return names, nil
+}
+
+// reverseName returns the in-addr.arpa or ip6.arpa name of addr
+func reverseName(addr netip.Addr) string {
+	addr = addr.Unmap()
+	var labels []string
+	if addr.Is4() {
+		octets := addr.As4()
+		for i := len(octets) - 1; i >= 0; i-- {
+			labels = append(labels, fmt.Sprint(octets[i]))
+		}
+		return strings.Join(labels, ".") + ".in-addr.arpa"
+	}
+
+	bytes := addr.As16()
+	for i := len(bytes) - 1; i >= 0; i-- {
+		labels = append(labels, fmt.Sprintf("%x", bytes[i]&0x0f), fmt.Sprintf("%x", bytes[i]>>4))
+	}
+	return strings.Join(labels, ".") + ".ip6.arpa"
+}
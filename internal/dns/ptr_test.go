@@ -0,0 +1,24 @@
+package dns
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"192.0.2.10", "10.2.0.192.in-addr.arpa"},
+		{"10.0.0.1", "1.0.0.10.in-addr.arpa"},
+		{"::ffff:192.0.2.10", "10.2.0.192.in-addr.arpa"},
+		{"2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"},
+		{"::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa"},
+	}
+	for _, tt := range tests {
+		if got := reverseName(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("reverseName(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
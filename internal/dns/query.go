@@ -0,0 +1,269 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ednsBufferSize is the UDP payload size advertised with EDNS(0), so large
+// responses are not truncated at 512 bytes
+const ednsBufferSize = 4096
+
+// ErrNXDOMAIN is returned when the queried name does not exist
+var ErrNXDOMAIN = errors.New("NXDOMAIN")
+
+// QueryCNAME asks the nameservers of the system for the CNAME record of host and returns
+// its target, or "" when host has no CNAME record. Unlike the Go resolver it
+// works for dangling records whose target does not resolve. A host that does
+// not exist has no CNAME record.
+func QueryCNAME(ctx context.Context, host string) (string, error) {
+	if useSystemResolver {
+		return systemQueryCNAME(ctx, host)
+	}
+
+	msg, err := query(ctx, host, dnsmessage.TypeCNAME)
+	if errors.Is(err, ErrNXDOMAIN) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, answer := range msg.Answers {
+		if cname, ok := answer.Body.(*dnsmessage.CNAMEResource); ok {
+			return strings.TrimSuffix(cname.CNAME.String(), "."), nil
+		}
+	}
+	return "", nil
+}
+
+// IsNXDOMAIN reports whether host does not exist in DNS
+func IsNXDOMAIN(ctx context.Context, host string) (bool, error) {
+	if useSystemResolver {
+		return systemIsNXDOMAIN(ctx, host)
+	}
+
+	_, err := query(ctx, host, dnsmessage.TypeA)
+	if errors.Is(err, ErrNXDOMAIN) {
+		return true, nil
+	}
+	return false, err
+}
+
+// query sends a recursive query for host to the nameservers of the system
+// and returns the response. An NXDOMAIN response is returned as ErrNXDOMAIN.
+func query(ctx context.Context, host string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	return systemConfig().query(ctx, host, qtype)
+}
+
+// Defaults of resolv.conf(5): the wait for an answer and the number of rounds
+// over the nameservers
+const (
+	defaultAttemptTimeout = 5 * time.Second
+	defaultAttempts       = 2
+)
+
+// resolverConfig lists the nameservers queries are sent to
+type resolverConfig struct {
+	servers  []string      // host:port
+	timeout  time.Duration // wait for an answer from one server
+	attempts int           // rounds over the servers
+}
+
+var (
+	systemConfigOnce sync.Once
+	systemConf       *resolverConfig
+)
+
+// systemConfig returns the configuration of /etc/resolv.conf, read once
+func systemConfig() *resolverConfig {
+	systemConfigOnce.Do(func() {
+		systemConf = readResolvConf("/etc/resolv.conf")
+	})
+	return systemConf
+}
+
+// readResolvConf reads every nameserver and the timeout and attempts options
+// of a resolv.conf file. Like the C library, it falls back to a resolver on
+// the local host when the file lists none.
+func readResolvConf(filename string) *resolverConfig {
+	conf := &resolverConfig{timeout: defaultAttemptTimeout, attempts: defaultAttempts}
+
+	file, err := os.Open(filename)
+	if err == nil {
+		defer file.Close()
+
+		lines := bufio.NewScanner(file)
+		for lines.Scan() {
+			fields := strings.Fields(lines.Text())
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "nameserver":
+				// Scoped IPv6 addresses keep their zone
+				if addr, err := netip.ParseAddr(fields[1]); err == nil {
+					conf.servers = append(conf.servers, net.JoinHostPort(addr.String(), "53"))
+				}
+			case "options":
+				for _, option := range fields[1:] {
+					name, value, _ := strings.Cut(option, ":")
+					n, err := strconv.Atoi(value)
+					if err != nil || n < 1 {
+						continue
+					}
+					switch name {
+					case "timeout":
+						conf.timeout = time.Duration(n) * time.Second
+					case "attempts":
+						conf.attempts = n
+					}
+				}
+			}
+		}
+	}
+
+	if len(conf.servers) == 0 {
+		conf.servers = []string{"127.0.0.1:53", "[::1]:53"}
+	}
+	return conf
+}
+
+// query sends the query to each nameserver in turn until one answers, for
+// the configured number of rounds. A server that times out, fails or answers
+// SERVFAIL or REFUSED is skipped; a truncated answer is asked again over
+// TCP.
+func (c *resolverConfig) query(ctx context.Context, host string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid name %q: %w", host, err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < c.attempts; attempt++ {
+		for _, server := range c.servers {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			response, err := c.exchange(ctx, server, "udp", name, qtype)
+			if err == nil && response.Truncated {
+				response, err = c.exchange(ctx, server, "tcp", name, qtype)
+			}
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			switch response.RCode {
+			case dnsmessage.RCodeSuccess:
+				return response, nil
+			case dnsmessage.RCodeNameError:
+				return response, ErrNXDOMAIN
+			default:
+				lastErr = fmt.Errorf("query for %s failed: %s", host, response.RCode)
+			}
+		}
+	}
+	return nil, lastErr
+}
+
+// exchange sends a single query to server over UDP or TCP and reads the
+// response
+func (c *resolverConfig) exchange(ctx context.Context, server, network string, name dnsmessage.Name, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	var idBytes [2]byte
+	rand.Read(idBytes[:])
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(ednsBufferSize, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+
+	request := dnsmessage.Message{
+		Header:      dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions:   []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+		Additionals: []dnsmessage.Resource{{Header: opt, Body: &dnsmessage.OPTResource{}}},
+	}
+	packed, err := request.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		return exchangeTCP(conn, packed, id)
+	}
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, ednsBufferSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var response dnsmessage.Message
+		if err := response.Unpack(buf[:n]); err != nil || response.ID != id {
+			// Ignore stray or malformed packets
+			continue
+		}
+		return &response, nil
+	}
+}
+
+// exchangeTCP sends the query with its two-byte length prefix and reads the
+// response, framed the same way
+func exchangeTCP(conn net.Conn, packed []byte, id uint16) (*dnsmessage.Message, error) {
+	framed := binary.BigEndian.AppendUint16(make([]byte, 0, len(packed)+2), uint16(len(packed)))
+	if _, err := conn.Write(append(framed, packed...)); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(buf); err != nil {
+		return nil, err
+	}
+	if response.ID != id {
+		return nil, fmt.Errorf("response ID %d does not match query ID %d", response.ID, id)
+	}
+	return &response, nil
+}
@@ -0,0 +1,221 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeServer is a nameserver on a UDP port of the loopback address
+type fakeServer struct {
+	addr    string
+	queries atomic.Int32 // UDP queries received
+}
+
+// startFakeServer serves queries with answer, which returns the response to
+// send or nil to drop the query. With tcp set it also serves TCP on the same
+// port.
+func startFakeServer(t *testing.T, tcp bool, answer func(n int32, network string, query *dnsmessage.Message) *dnsmessage.Message) *fakeServer {
+	t.Helper()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { udp.Close() })
+	server := &fakeServer{addr: udp.LocalAddr().String()}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil {
+				continue
+			}
+			if response := answer(server.queries.Add(1), "udp", &query); response != nil {
+				packed, _ := response.Pack()
+				udp.WriteTo(packed, from)
+			}
+		}
+	}()
+
+	if tcp {
+		listener, err := net.Listen("tcp", server.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { listener.Close() })
+
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					conn.Close()
+					continue
+				}
+				buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, buf); err != nil {
+					conn.Close()
+					continue
+				}
+				var query dnsmessage.Message
+				if query.Unpack(buf) == nil {
+					if response := answer(0, "tcp", &query); response != nil {
+						packed, _ := response.Pack()
+						conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(packed))), packed...))
+					}
+				}
+				conn.Close()
+			}
+		}()
+	}
+	return server
+}
+
+// cnameResponse answers query with a CNAME record pointing at target
+func cnameResponse(query *dnsmessage.Message, target string) *dnsmessage.Message {
+	return &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+		Questions: query.Questions,
+		Answers: []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 300},
+			Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(target)},
+		}},
+	}
+}
+
+// queryTarget runs a CNAME query with conf and returns the target answered
+func queryTarget(t *testing.T, conf *resolverConfig) string {
+	t.Helper()
+
+	msg, err := conf.query(context.Background(), "app.example.com", dnsmessage.TypeCNAME)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(msg.Answers) != 1 {
+		t.Fatalf("got %d answers, want 1", len(msg.Answers))
+	}
+	return msg.Answers[0].Body.(*dnsmessage.CNAMEResource).CNAME.String()
+}
+
+func TestQueryRetriesLostPacket(t *testing.T) {
+	server := startFakeServer(t, false, func(n int32, _ string, query *dnsmessage.Message) *dnsmessage.Message {
+		if n == 1 {
+			return nil // the first packet is lost
+		}
+		return cnameResponse(query, "app.azurewebsites.net.")
+	})
+
+	conf := &resolverConfig{servers: []string{server.addr}, timeout: 200 * time.Millisecond, attempts: 2}
+	if got := queryTarget(t, conf); got != "app.azurewebsites.net." {
+		t.Errorf("target = %q, want %q", got, "app.azurewebsites.net.")
+	}
+	if got := server.queries.Load(); got != 2 {
+		t.Errorf("server received %d queries, want 2", got)
+	}
+}
+
+func TestQueryFallsThroughToNextServer(t *testing.T) {
+	dead := startFakeServer(t, false, func(int32, string, *dnsmessage.Message) *dnsmessage.Message {
+		return nil
+	})
+	live := startFakeServer(t, false, func(_ int32, _ string, query *dnsmessage.Message) *dnsmessage.Message {
+		return cnameResponse(query, "app.herokudns.com.")
+	})
+
+	conf := &resolverConfig{servers: []string{dead.addr, live.addr}, timeout: 200 * time.Millisecond, attempts: 1}
+	if got := queryTarget(t, conf); got != "app.herokudns.com." {
+		t.Errorf("target = %q, want %q", got, "app.herokudns.com.")
+	}
+}
+
+func TestQuerySkipsServerFailure(t *testing.T) {
+	failing := startFakeServer(t, false, func(_ int32, _ string, query *dnsmessage.Message) *dnsmessage.Message {
+		return &dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: dnsmessage.RCodeServerFailure},
+			Questions: query.Questions,
+		}
+	})
+	live := startFakeServer(t, false, func(_ int32, _ string, query *dnsmessage.Message) *dnsmessage.Message {
+		return cnameResponse(query, "app.github.io.")
+	})
+
+	conf := &resolverConfig{servers: []string{failing.addr, live.addr}, timeout: time.Second, attempts: 1}
+	if got := queryTarget(t, conf); got != "app.github.io." {
+		t.Errorf("target = %q, want %q", got, "app.github.io.")
+	}
+}
+
+func TestQueryFallsBackToTCP(t *testing.T) {
+	server := startFakeServer(t, true, func(_ int32, network string, query *dnsmessage.Message) *dnsmessage.Message {
+		if network == "udp" {
+			return &dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Truncated: true},
+				Questions: query.Questions,
+			}
+		}
+		return cnameResponse(query, "app.s3.amazonaws.com.")
+	})
+
+	conf := &resolverConfig{servers: []string{server.addr}, timeout: time.Second, attempts: 1}
+	if got := queryTarget(t, conf); got != "app.s3.amazonaws.com." {
+		t.Errorf("target = %q, want %q", got, "app.s3.amazonaws.com.")
+	}
+}
+
+func TestReadResolvConf(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    resolverConfig
+	}{
+		{
+			name:    "every nameserver",
+			content: "# comment\nnameserver 192.0.2.1\nnameserver 2001:db8::53\nsearch example.com\nnameserver 198.51.100.1\n",
+			want:    resolverConfig{servers: []string{"192.0.2.1:53", "[2001:db8::53]:53", "198.51.100.1:53"}, timeout: defaultAttemptTimeout, attempts: defaultAttempts},
+		},
+		{
+			name:    "options",
+			content: "nameserver 192.0.2.1\noptions ndots:2 timeout:1 attempts:3\n",
+			want:    resolverConfig{servers: []string{"192.0.2.1:53"}, timeout: time.Second, attempts: 3},
+		},
+		{
+			name:    "invalid entries skipped",
+			content: "nameserver resolver.example.com\nnameserver 192.0.2.1\noptions timeout:0 attempts:x\n",
+			want:    resolverConfig{servers: []string{"192.0.2.1:53"}, timeout: defaultAttemptTimeout, attempts: defaultAttempts},
+		},
+		{
+			name:    "no nameserver",
+			content: "search example.com\n",
+			want:    resolverConfig{servers: []string{"127.0.0.1:53", "[::1]:53"}, timeout: defaultAttemptTimeout, attempts: defaultAttempts},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "resolv.conf")
+			if err := os.WriteFile(filename, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := readResolvConf(filename); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("readResolvConf() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Record is an answer record returned by the resolver
+type Record struct {
+	Name  string
+	TTL   uint32
+	Type  string
+	Value string
+}
+
+// Records queries the A and AAAA records of host and returns every answer,
+// including the CNAME records the resolver followed to reach them. When host
+// or the end of its chain does not exist, the answers seen so far are
+// returned along with ErrNXDOMAIN.
+func Records(ctx context.Context, host string) ([]Record, error) {
+	if useSystemResolver {
+		return systemRecords(ctx, host)
+	}
+
+	var records []Record
+	seen := make(map[Record]bool)
+	nxdomain := false
+
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		msg, err := query(ctx, host, qtype)
+		if errors.Is(err, ErrNXDOMAIN) {
+			nxdomain = true
+		} else if err != nil {
+			return records, err
+		}
+
+		for _, answer := range msg.Answers {
+			record, ok := toRecord(answer)
+			if !ok || seen[record] {
+				continue
+			}
+			seen[record] = true
+			records = append(records, record)
+		}
+	}
+
+	if nxdomain {
+		return records, ErrNXDOMAIN
+	}
+	return records, nil
+}
+
+// toRecord converts an A, AAAA or CNAME answer; other types are skipped
+func toRecord(answer dnsmessage.Resource) (Record, bool) {
+	record := Record{
+		Name: strings.TrimSuffix(answer.Header.Name.String(), "."),
+		TTL:  answer.Header.TTL,
+	}
+
+	switch body := answer.Body.(type) {
+	case *dnsmessage.AResource:
+		record.Type = "A"
+		record.Value = net.IP(body.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		record.Type = "AAAA"
+		record.Value = net.IP(body.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		record.Type = "CNAME"
+		record.Value = strings.TrimSuffix(body.CNAME.String(), ".")
+	default:
+		return Record{}, false
+	}
+	return record, true
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package dns
+
+// useSystemResolver is true where there is no /etc/resolv.conf to read the
+// nameservers from, such as Windows, so lookups go through the resolver of
+// the system
+const useSystemResolver = true
@@ -0,0 +1,7 @@
+//go:build unix
+
+package dns
+
+// useSystemResolver is false on Unix, where queries are sent straight to the
+// nameservers of /etc/resolv.conf
+const useSystemResolver = false
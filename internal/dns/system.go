@@ -0,0 +1,95 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// The lookups below go through the resolver of the system, for platforms
+// without /etc/resolv.conf. It may follow a CNAME chain further than one hop
+// at a time and does not report TTLs.
+
+// systemQueryCNAME is QueryCNAME through the system resolver
+func systemQueryCNAME(ctx context.Context, host string) (string, error) {
+	cname, err := net.DefaultResolver.LookupCNAME(ctx, host)
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// A name without a CNAME record is returned as its own canonical name
+	cname = strings.TrimSuffix(cname, ".")
+	if strings.EqualFold(cname, strings.TrimSuffix(host, ".")) {
+		return "", nil
+	}
+	return cname, nil
+}
+
+// systemIsNXDOMAIN is IsNXDOMAIN through the system resolver
+func systemIsNXDOMAIN(ctx context.Context, host string) (bool, error) {
+	_, err := net.DefaultResolver.LookupHost(ctx, host)
+	if isNotFound(err) {
+		return true, nil
+	}
+	return false, err
+}
+
+// systemLookupPTR is LookupPTR through the system resolver
+func systemLookupPTR(ctx context.Context, addr netip.Addr) ([]string, error) {
+	hosts, err := net.DefaultResolver.LookupAddr(ctx, addr.Unmap().String())
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		names = append(names, strings.ToLower(strings.TrimSuffix(host, ".")))
+	}
+	return names, nil
+}
+
+// systemRecords is Records through the system resolver. TTLs are reported as
+// 0.
+func systemRecords(ctx context.Context, host string) ([]Record, error) {
+	chain, err := ResolveCNAME(ctx, host, DefaultMaxCNAMEDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	name := strings.TrimSuffix(host, ".")
+	for _, target := range chain {
+		records = append(records, Record{Name: name, Type: "CNAME", Value: target})
+		name = target
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if isNotFound(err) {
+		return records, ErrNXDOMAIN
+	}
+	if err != nil {
+		return records, err
+	}
+	for _, addr := range addrs {
+		record := Record{Name: name, Type: "AAAA", Value: addr.IP.String()}
+		if addr.IP.To4() != nil {
+			record.Type = "A"
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// isNotFound reports whether err is a lookup of a name that does not exist
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,101 @@
+package fingerprints
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bundleName is the name of the cached fingerprint bundle in the user cache
+// directory
+const bundleName = "fingerprints-bundle.yaml"
+
+// BundleFile returns the path of the fingerprint bundle cached by
+// `fingerprints update`
+func BundleFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "subtake", bundleName), nil
+}
+
+// ParseBundle parses a fingerprint bundle in the subtake format, JSON or
+// YAML. Unlike custom files, a downloaded bundle does not expand environment
+// variables.
+func ParseBundle(data []byte) (*Fingerprints, error) {
+	var fp Fingerprints
+	if err := yaml.Unmarshal(data, &fp); err != nil {
+		return nil, fmt.Errorf("failed to parse fingerprint bundle: %w", err)
+	}
+	if len(fp.Fingerprints) == 0 {
+		return nil, fmt.Errorf("fingerprint bundle has no fingerprints")
+	}
+	return &fp, nil
+}
+
+// SaveBundle verifies that data is a fingerprint bundle and replaces the
+// cached bundle with it. It returns the parsed bundle and the path of the
+// cached bundle.
+func SaveBundle(data []byte) (*Fingerprints, string, error) {
+	fp, err := ParseBundle(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename, err := BundleFile()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return nil, "", err
+	}
+
+	// Scans starting meanwhile read either the old or the new bundle
+	tmp, err := os.CreateTemp(filepath.Dir(filename), bundleName+".*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, "", err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return nil, "", err
+	}
+	return fp, filename, nil
+}
+
+// loadBundle loads the cached fingerprint bundle, or returns nil when
+// `fingerprints update` was never run
+func loadBundle() (*Fingerprints, error) {
+	filename, err := BundleFile()
+	if err != nil {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := ParseBundle(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for i := range fp.Fingerprints {
+		fp.Fingerprints[i].Source = filename
+	}
+	return fp, nil
+}
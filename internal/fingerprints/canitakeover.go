@@ -0,0 +1,75 @@
+package fingerprints
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Formats of custom fingerprint files
+const (
+	FormatSubtake      = "subtake"
+	FormatCanITakeOver = "canitakeover"
+)
+
+// Formats lists the supported fingerprint file formats
+var Formats = []string{FormatSubtake, FormatCanITakeOver}
+
+// canITakeOverEntry is an entry of the fingerprints.json file of the
+// can-i-take-over-xyz project
+type canITakeOverEntry struct {
+	Service       string   `json:"service"`
+	CNAME         []string `json:"cname"`
+	Fingerprint   string   `json:"fingerprint"`
+	HTTPStatus    *int     `json:"http_status"`
+	NXDOMAIN      bool     `json:"nxdomain"`
+	Status        string   `json:"status"`
+	Vulnerable    bool     `json:"vulnerable"`
+	Documentation string   `json:"documentation"`
+}
+
+// importCanITakeOver converts a can-i-take-over-xyz fingerprints.json file.
+// Services listed as not vulnerable are skipped, and so are those detected
+// by an NXDOMAIN answer rather than a body, which passive mode covers.
+// Edge cases are imported with a low severity.
+func importCanITakeOver(data []byte) (*Fingerprints, error) {
+	var entries []canITakeOverEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse can-i-take-over-xyz fingerprints: %w", err)
+	}
+
+	fp := &Fingerprints{}
+	skipped := 0
+	for _, entry := range entries {
+		if !entry.Vulnerable || entry.NXDOMAIN || strings.TrimSpace(entry.Fingerprint) == "" || entry.Fingerprint == "NXDOMAIN" {
+			skipped++
+			continue
+		}
+
+		notes := "Imported from can-i-take-over-xyz"
+		if entry.Status != "" {
+			notes += " (" + entry.Status + ")"
+		}
+		if entry.Documentation != "" {
+			notes += ", see " + entry.Documentation
+		}
+
+		fingerprint := Fingerprint{
+			Service: entry.Service,
+			Pattern: entry.Fingerprint,
+			Notes:   notes,
+			CNAME:   entry.CNAME,
+		}
+		if entry.HTTPStatus != nil {
+			fingerprint.StatusCodes = []int{*entry.HTTPStatus}
+		}
+		if strings.EqualFold(entry.Status, "Edge case") {
+			fingerprint.Severity = SeverityLow
+		}
+		fp.Fingerprints = append(fp.Fingerprints, fingerprint)
+	}
+
+	slog.Info("imported can-i-take-over-xyz fingerprints", "imported", len(fp.Fingerprints), "skipped", skipped)
+	return fp, nil
+}
@@ -0,0 +1,86 @@
+package fingerprints
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Explanation tells whether a fingerprint matched a target and why
+type Explanation struct {
+	Fingerprint Fingerprint
+	Evaluated   bool   // false when the fingerprint was skipped
+	Match       *Match // nil when it did not match
+	Reason      string
+}
+
+// Explain evaluates every fingerprint against the target like Match does,
+// without stopping at a score, and records why each one did or did not match
+func (fp *Fingerprints) Explain(target *Target) []Explanation {
+	explanations := make([]Explanation, 0, len(fp.Fingerprints))
+	for i := range fp.Fingerprints {
+		explanations = append(explanations, fp.Fingerprints[i].explain(target))
+	}
+	return explanations
+}
+
+// explain evaluates the fingerprint in the order MatchTarget does, stopping
+// at the first condition that fails
+func (f *Fingerprint) explain(target *Target) Explanation {
+	explanation := Explanation{Fingerprint: *f}
+	switch {
+	case !f.IsEnabled():
+		explanation.Reason = "disabled"
+		return explanation
+	case f.Request != nil:
+		explanation.Reason = fmt.Sprintf("matched against its own request (%s), not the GET", f.Request)
+		return explanation
+	}
+
+	explanation.Evaluated = true
+	if !f.InStatusRange(target.StatusCode) {
+		explanation.Reason = fmt.Sprintf("status %d out of range (%s)", target.StatusCode, f.statusRange())
+		return explanation
+	}
+	if f.Header != nil && !f.Header.Match(target.Headers) {
+		explanation.Reason = fmt.Sprintf("header mismatch: no %q header", f.Header)
+		return explanation
+	}
+
+	match, err := f.MatchTarget(target)
+	switch {
+	case err != nil:
+		explanation.Reason = fmt.Sprintf("regex does not compile: %v", err)
+	case match != nil:
+		explanation.Match = match
+		explanation.Reason = fmt.Sprintf("matched on %s, weight %d", match.Field, match.Weight)
+	case f.Regex:
+		explanation.Reason = "regex compiled but no hit in the body"
+	default:
+		explanation.Reason = "pattern absent from the body"
+	}
+	return explanation
+}
+
+// statusRange describes the status codes the fingerprint applies to, e.g.
+// "404 or 410" or "400-499"
+func (f *Fingerprint) statusRange() string {
+	var conditions []string
+	if len(f.StatusCodes) > 0 {
+		codes := make([]string, len(f.StatusCodes))
+		for i, code := range f.StatusCodes {
+			codes[i] = strconv.Itoa(code)
+		}
+		conditions = append(conditions, strings.Join(codes, " or "))
+	}
+
+	switch {
+	case f.MinStatus > 0 && f.MaxStatus > 0:
+		conditions = append(conditions, fmt.Sprintf("%d-%d", f.MinStatus, f.MaxStatus))
+	case f.MinStatus > 0:
+		conditions = append(conditions, fmt.Sprintf("%d or above", f.MinStatus))
+	case f.MaxStatus > 0:
+		conditions = append(conditions, fmt.Sprintf("%d or below", f.MaxStatus))
+	}
+	return strings.Join(conditions, ", ")
+}
@@ -1,21 +1,196 @@
 package fingerprints
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io/fs"
+	"log/slog"
+	"net/http"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Weights contributed by a matching fingerprint to the verdict score
+const (
+	WeightCNAME  = 10 // pattern matched and the CNAME chain points at the service
+	WeightString = 5  // service specific string matched the body
+	WeightRegex  = 2  // regex matched the body
+	WeightHeader = 3  // header matched and the fingerprint has no body pattern
+
+	// WeightConfirmed is added when the active confirmation probe succeeds
+	WeightConfirmed = 10
+
+	// Weights of the DNS signals used in passive mode
+	WeightDangling     = 5 // CNAME target does not resolve
+	WeightServiceCNAME = 2 // CNAME chain points at a known service
+
+	// WeightCloudIP is given with --compare-ip to an address in a cloud
+	// provider range that serves nothing
+	WeightCloudIP = 5
+)
+
+// DefaultThreshold is the minimum score for a subdomain to be vulnerable
+const DefaultThreshold = 4
+
 // Fingerprint represents a single fingerprint pattern
 type Fingerprint struct {
-	Service string `json:"service" yaml:"service"`
-	Pattern string `json:"pattern" yaml:"pattern"`
-	Notes   string `json:"notes" yaml:"notes"`
-	Regex   bool   `json:"regex" yaml:"regex"`
+	// ID identifies the fingerprint in evidence. It is generated from the
+	// service and the match definition when not set.
+	ID      string   `json:"id,omitempty" yaml:"id,omitempty"`
+	Service string   `json:"service" yaml:"service"`
+	Pattern string   `json:"pattern" yaml:"pattern"`
+	Notes   string   `json:"notes" yaml:"notes"`
+	Regex   bool     `json:"regex" yaml:"regex"`
+	CNAME   []string `json:"cname,omitempty" yaml:"cname,omitempty"`
+	Example string   `json:"example,omitempty" yaml:"example,omitempty"`
+	Confirm *Confirm `json:"confirm,omitempty" yaml:"confirm,omitempty"`
+
+	// Request is sent instead of relying on the GET of the subdomain, for
+	// services that only reveal their state in answer to e.g. a POST. The
+	// fingerprint is matched against that response alone, and only with
+	// --allow-post.
+	Request *Request `json:"request,omitempty" yaml:"request,omitempty"`
+
+	// MinStatus and MaxStatus restrict the fingerprint to responses whose
+	// status code falls in the range (0 = unbounded)
+	MinStatus int `json:"min_status,omitempty" yaml:"min_status,omitempty"`
+	MaxStatus int `json:"max_status,omitempty" yaml:"max_status,omitempty"`
+
+	// StatusCodes restricts the fingerprint to the listed status codes
+	StatusCodes []int `json:"status_codes,omitempty" yaml:"status_codes,omitempty"`
+
+	// Header requires a response header. Fingerprints without a pattern
+	// match on status and headers alone.
+	Header *HeaderMatch `json:"header,omitempty" yaml:"header,omitempty"`
+
+	// Severity is the impact of a takeover of the service: critical, high,
+	// medium or low (default medium)
+	Severity string `json:"severity,omitempty" yaml:"severity,omitempty"`
+
+	// Enabled set to false keeps the fingerprint in the file, notes and all,
+	// but skips it during matching (default true)
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+
+	// Source is where the fingerprint was loaded from: SourceDefault or the
+	// path of a custom file
+	Source string `json:"-" yaml:"-"`
+}
+
+// Severity levels, from least to most severe
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Severities lists the severity levels from least to most severe
+var Severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// SeverityRank orders severities from 1 (low) to 4 (critical); unknown
+// values rank 0
+func SeverityRank(severity string) int {
+	for i, level := range Severities {
+		if strings.EqualFold(level, severity) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// SeverityLevel returns the severity of the fingerprint, medium when unset
+func (f *Fingerprint) SeverityLevel() string {
+	if f.Severity == "" {
+		return SeverityMedium
+	}
+	return strings.ToLower(f.Severity)
+}
+
+// IsEnabled reports whether the fingerprint is used for matching
+func (f *Fingerprint) IsEnabled() bool {
+	return f.Enabled == nil || *f.Enabled
+}
+
+// Enabled returns the fingerprints that are used for matching
+func (fp *Fingerprints) Enabled() *Fingerprints {
+	enabled := &Fingerprints{}
+	for _, fingerprint := range fp.Fingerprints {
+		if fingerprint.IsEnabled() {
+			enabled.Fingerprints = append(enabled.Fingerprints, fingerprint)
+		}
+	}
+	return enabled
+}
+
+// SourceDefault is the source of the built-in fingerprints
+const SourceDefault = "default"
+
+// HeaderMatch requires a response header to be present and, when Value is
+// set, to contain it (case-insensitive)
+type HeaderMatch struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Confirm describes an active probe that confirms a passive match, e.g. a
+// request for a unique path that only an unclaimed resource answers in a
+// specific way
+type Confirm struct {
+	Method  string `json:"method,omitempty" yaml:"method,omitempty"`   // defaults to GET
+	Path    string `json:"path" yaml:"path"`                           // {random} is replaced with a random token
+	Status  int    `json:"status,omitempty" yaml:"status,omitempty"`   // expected status code (0 = any)
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"` // expected body content
+	Regex   bool   `json:"regex,omitempty" yaml:"regex,omitempty"`
+}
+
+// Request describes the request a fingerprint is matched against
+type Request struct {
+	Method      string `json:"method,omitempty" yaml:"method,omitempty"`             // defaults to POST
+	Path        string `json:"path,omitempty" yaml:"path,omitempty"`                 // defaults to /
+	ContentType string `json:"content_type,omitempty" yaml:"content_type,omitempty"` // e.g. application/json
+	Body        string `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Target holds the response data fingerprints are matched against
+type Target struct {
+	StatusCode int
+	Body       string
+	Headers    http.Header
+	CNAME      []string
+
+	// Service is the service the CNAME chain points at according to the
+	// service registry; fingerprints of that service count as CNAME-confirmed
+	Service string
+}
+
+// Fields of the target a fingerprint can match on
+const (
+	FieldBody   = "body"
+	FieldHeader = "header"
+	FieldCNAME  = "cname"
+	FieldStatus = "status"
+	FieldDNS    = "dns"
+
+	// FieldExternal marks evidence produced by an external matcher command
+	FieldExternal = "external"
+)
+
+// Match represents a fingerprint that matched a target
+type Match struct {
+	Fingerprint Fingerprint
+	Weight      int
+	Field       string
 }
 
 // Fingerprints holds a collection of fingerprints
@@ -23,30 +198,86 @@ type Fingerprints struct {
 	Fingerprints []Fingerprint `json:"fingerprints" yaml:"fingerprints"`
 }
 
-// Load loads fingerprints from default and custom files
-func Load(customFile string) (*Fingerprints, error) {
+// Load loads fingerprints from default and custom files. The custom file is
+// in the given format, one of Formats; an empty format is FormatSubtake. The
+// bundle cached by `fingerprints update`, if any, is added to the defaults.
+func Load(customFile, format string) (*Fingerprints, error) {
 	// Load default fingerprints
 	defaultFp := GetDefaultFingerprints()
+	for i := range defaultFp.Fingerprints {
+		defaultFp.Fingerprints[i].Source = SourceDefault
+	}
+
+	bundle, err := loadBundle()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fingerprint bundle: %w", err)
+	}
+	if bundle != nil {
+		defaultFp.Fingerprints = append(defaultFp.Fingerprints, bundle.Fingerprints...)
+	}
+	defaultFp.assignIDs()
 
 	if customFile == "" {
-		return defaultFp, nil
+		return defaultFp.usable()
 	}
 
 	// Load custom fingerprints
-	customFp, err := loadFromFile(customFile)
+	customFp, err := loadFromFile(customFile, format)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load custom fingerprints: %w", err)
 	}
 
+	customFp.assignIDs()
+
 	// Merge custom fingerprints with default ones
 	merged := &Fingerprints{
 		Fingerprints: append(defaultFp.Fingerprints, customFp.Fingerprints...),
 	}
 
-	return merged, nil
+	return merged.usable()
+}
+
+// ErrNoFingerprints is returned when no usable fingerprint was loaded
+var ErrNoFingerprints = errors.New("no fingerprints loaded; nothing to match")
+
+// usable drops the fingerprints with an invalid regex, warning about each,
+// and fails when none are left enabled, since a scan would then match nothing
+func (fp *Fingerprints) usable() (*Fingerprints, error) {
+	valid := fp.Fingerprints[:0:0]
+	enabled := 0
+	for _, fingerprint := range fp.Fingerprints {
+		if fingerprint.Severity != "" && SeverityRank(fingerprint.Severity) == 0 {
+			slog.Warn("unknown fingerprint severity, using medium", "id", fingerprint.ID,
+				"severity", fingerprint.Severity, "source", fingerprint.Source)
+			fingerprint.Severity = SeverityMedium
+		}
+		if fingerprint.Regex {
+			if _, err := regexp.Compile(fingerprint.Pattern); err != nil {
+				slog.Warn("skipping fingerprint with invalid regex", "id", fingerprint.ID,
+					"service", fingerprint.Service, "source", fingerprint.Source, "error", err)
+				continue
+			}
+		}
+		valid = append(valid, fingerprint)
+		if fingerprint.IsEnabled() {
+			enabled++
+		}
+	}
+
+	if enabled == 0 {
+		switch {
+		case len(valid) > 0:
+			return nil, fmt.Errorf("%w (all %d fingerprints are disabled)", ErrNoFingerprints, len(valid))
+		case len(fp.Fingerprints) > 0:
+			return nil, fmt.Errorf("%w (all %d fingerprints have an invalid regex)", ErrNoFingerprints, len(fp.Fingerprints))
+		}
+		return nil, ErrNoFingerprints
+	}
+
+	return &Fingerprints{Fingerprints: valid}, nil
 }
 
-func loadFromFile(filename string) (*Fingerprints, error) {
+func loadFromFile(filename, format string) (*Fingerprints, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -54,10 +285,19 @@ func loadFromFile(filename string) (*Fingerprints, error) {
 
 	var fp Fingerprints
 
-	// Try JSON first, then YAML
-	if strings.HasSuffix(strings.ToLower(filename), ".json") {
+	switch {
+	case format == FormatCanITakeOver:
+		imported, err := importCanITakeOver(data)
+		if err != nil {
+			return nil, err
+		}
+		fp = *imported
+	case format != "" && format != FormatSubtake:
+		return nil, fmt.Errorf("unknown fingerprints format %q (expected one of %s)", format, strings.Join(Formats, ", "))
+	case strings.HasSuffix(strings.ToLower(filename), ".json"):
+		// Try JSON first, then YAML
 		err = json.Unmarshal(data, &fp)
-	} else {
+	default:
 		err = yaml.Unmarshal(data, &fp)
 	}
 
@@ -65,29 +305,183 @@ func loadFromFile(filename string) (*Fingerprints, error) {
 		return nil, fmt.Errorf("failed to parse fingerprints file: %w", err)
 	}
 
+	for i := range fp.Fingerprints {
+		fingerprint := &fp.Fingerprints[i]
+		fingerprint.Source = filename
+
+		for _, field := range []*string{&fingerprint.Pattern, &fingerprint.Notes} {
+			if *field, err = expandEnv(*field); err != nil {
+				return nil, fmt.Errorf("fingerprint %d (%s): %w", i+1, fingerprint.Service, err)
+			}
+		}
+	}
+
 	return &fp, nil
 }
 
-// Match checks if the given content matches any fingerprint
-func (fp *Fingerprints) Match(content string, headers map[string]string) ([]Fingerprint, error) {
-	var matches []Fingerprint
+// envPlaceholder matches ${VAR} and ${VAR:-default} placeholders, and the
+// escaped $${ that stands for a literal ${
+var envPlaceholder = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// expandEnv replaces ${VAR} placeholders with the value of the environment
+// variable. An undefined variable is an error unless a default is given with
+// ${VAR:-default}; an empty default is used with a warning.
+func expandEnv(s string) (string, error) {
+	var err error
+	expanded := envPlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		if placeholder == "$${" {
+			return "${"
+		}
+
+		groups := envPlaceholder.FindStringSubmatch(placeholder)
+		name, def := groups[1], groups[2]
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+
+		switch {
+		case def == "":
+			if err == nil {
+				err = fmt.Errorf("environment variable %s is not set", name)
+			}
+		case def == ":-":
+			slog.Warn("environment variable is not set, expanding to empty", "variable", name)
+		}
+		return strings.TrimPrefix(def, ":-")
+	})
+	return expanded, err
+}
+
+// assignIDs generates the ID of every fingerprint that has none
+func (fp *Fingerprints) assignIDs() {
+	for i := range fp.Fingerprints {
+		if fp.Fingerprints[i].ID == "" {
+			fp.Fingerprints[i].ID = fp.Fingerprints[i].generateID()
+		}
+	}
+}
+
+// generateID derives a stable ID from the service name and a hash of what the
+// fingerprint matches, e.g. "aws-s3-1a2b3c4d"
+func (f *Fingerprint) generateID() string {
+	definition := fmt.Sprintf("%s\x00%s\x00%t\x00%s\x00%v", f.Service, f.Pattern, f.Regex, f.Describe(), f.StatusCodes)
+	if f.Request != nil {
+		// Kept out of the definition otherwise, so that existing IDs stay
+		definition += fmt.Sprintf("\x00%s\x00%s\x00%s\x00%s", f.Request.Method, f.Request.Path, f.Request.ContentType, f.Request.Body)
+	}
+	sum := sha256.Sum256([]byte(definition))
+
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(f.Service), "-"), "-")
+	if slug == "" {
+		slug = "fingerprint"
+	}
+	return slug + "-" + hex.EncodeToString(sum[:4])
+}
+
+// nonSlug matches the characters replaced when building an ID from a service
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Match returns every fingerprint that matches the target along with its weight
+func (fp *Fingerprints) Match(target *Target) ([]Match, error) {
+	return fp.MatchUntil(target, 0)
+}
+
+// MatchUntil is like Match but stops evaluating fingerprints once the weights
+// of the matches found add up to score, e.g. the threshold of a vulnerable
+// verdict. A score of 0 evaluates every fingerprint.
+func (fp *Fingerprints) MatchUntil(target *Target, score int) ([]Match, error) {
+	var matches []Match
+	total := 0
 
 	for _, fingerprint := range fp.Fingerprints {
-		matched, err := fingerprint.Match(content, headers)
+		// Fingerprints with their own request are matched against its
+		// response by the scanner
+		if !fingerprint.IsEnabled() || fingerprint.Request != nil {
+			continue
+		}
+
+		match, err := fingerprint.MatchTarget(target)
 		if err != nil {
 			return nil, err
 		}
 
-		if matched {
-			matches = append(matches, fingerprint)
+		if match != nil {
+			matches = append(matches, *match)
+
+			total += match.Weight
+			if score > 0 && total >= score {
+				break
+			}
 		}
 	}
 
 	return matches, nil
 }
 
-// Match checks if the fingerprint matches the given content
-func (f *Fingerprint) Match(content string, headers map[string]string) (bool, error) {
+// MatchTarget matches the fingerprint against the target and returns the
+// match with its weight, or nil
+func (f *Fingerprint) MatchTarget(target *Target) (*Match, error) {
+	if !f.InStatusRange(target.StatusCode) {
+		return nil, nil
+	}
+
+	matched, err := f.Match(target.Body, target.Headers)
+	if err != nil || !matched {
+		return nil, err
+	}
+
+	cnameMatched := f.MatchCNAME(target.CNAME) ||
+		(target.Service != "" && strings.EqualFold(f.Service, target.Service))
+	field := FieldBody
+	switch {
+	case cnameMatched:
+		field = FieldCNAME
+	case f.Pattern == "":
+		field = FieldHeader
+	}
+
+	return &Match{
+		Fingerprint: *f,
+		Weight:      f.Weight(cnameMatched),
+		Field:       field,
+	}, nil
+}
+
+// WithRequest returns the enabled fingerprints that define their own request
+func (fp *Fingerprints) WithRequest() []Fingerprint {
+	var withRequest []Fingerprint
+	for _, fingerprint := range fp.Fingerprints {
+		if fingerprint.IsEnabled() && fingerprint.Request != nil {
+			withRequest = append(withRequest, fingerprint)
+		}
+	}
+	return withRequest
+}
+
+// Match checks if the fingerprint matches the given content and headers. A
+// fingerprint without a pattern matches on its header requirement alone.
+// Services that encode their error text with HTML entities, e.g. "isn&#39;t",
+// match on the decoded content as well.
+func (f *Fingerprint) Match(content string, headers http.Header) (bool, error) {
+	if f.Header != nil && !f.Header.Match(headers) {
+		return false, nil
+	}
+	if f.Pattern == "" {
+		return f.Header != nil, nil
+	}
+
+	matched, err := f.matchContent(content)
+	if matched || err != nil {
+		return matched, err
+	}
+	if decoded := html.UnescapeString(content); decoded != content {
+		return f.matchContent(decoded)
+	}
+	return false, nil
+}
+
+// matchContent checks if the pattern matches the content
+func (f *Fingerprint) matchContent(content string) (bool, error) {
 	if f.Regex {
 		re, err := regexp.Compile(f.Pattern)
 		if err != nil {
@@ -100,207 +494,215 @@ func (f *Fingerprint) Match(content string, headers map[string]string) (bool, er
 	return strings.Contains(strings.ToLower(content), strings.ToLower(f.Pattern)), nil
 }
 
+// Find returns the position of the first match of the pattern in content as
+// a start and end offset, or nil when it does not match
+func (f *Fingerprint) Find(content string) ([]int, error) {
+	re, err := f.compile()
+	if err != nil {
+		return nil, err
+	}
+	return re.FindStringIndex(content), nil
+}
+
+// FindAll returns the positions of every non-overlapping match of the
+// pattern in content, in order
+func (f *Fingerprint) FindAll(content string) ([][]int, error) {
+	re, err := f.compile()
+	if err != nil {
+		return nil, err
+	}
+	return re.FindAllStringIndex(content, -1), nil
+}
+
+// compile returns the pattern as a regular expression
+func (f *Fingerprint) compile() (*regexp.Regexp, error) {
+	pattern := f.Pattern
+	if !f.Regex {
+		// Plain patterns match case-insensitively, as in Match
+		pattern = "(?i)" + regexp.QuoteMeta(pattern)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern %s: %w", f.Pattern, err)
+	}
+	return re, nil
+}
+
+// Describe returns a short description of what the fingerprint matches: its
+// pattern, or its header requirement when it has no pattern
+func (f *Fingerprint) Describe() string {
+	if f.Pattern == "" && f.Header != nil {
+		return f.Header.String()
+	}
+	return f.Pattern
+}
+
+// Match checks if the headers satisfy the requirement
+func (h *HeaderMatch) Match(headers http.Header) bool {
+	for _, value := range headers.Values(h.Name) {
+		if strings.Contains(strings.ToLower(value), strings.ToLower(h.Value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the requirement as a header line
+func (h *HeaderMatch) String() string {
+	if h.Value == "" {
+		return h.Name
+	}
+	return h.Name + ": " + h.Value
+}
+
+// CanonicalBody returns a response body the fingerprint is expected to match:
+// the example if one is set, otherwise the pattern of a plain string
+// fingerprint
+func (f *Fingerprint) CanonicalBody() string {
+	if f.Example != "" || f.Regex {
+		return f.Example
+	}
+	return f.Pattern
+}
+
+// InStatusRange checks if the status code is within the fingerprint's bounds
+func (f *Fingerprint) InStatusRange(statusCode int) bool {
+	if len(f.StatusCodes) > 0 && !slices.Contains(f.StatusCodes, statusCode) {
+		return false
+	}
+	if f.MinStatus > 0 && statusCode < f.MinStatus {
+		return false
+	}
+	if f.MaxStatus > 0 && statusCode > f.MaxStatus {
+		return false
+	}
+	return true
+}
+
+// MatchCNAME checks if any hop of the CNAME chain ends with one of the
+// fingerprint's CNAME suffixes
+func (f *Fingerprint) MatchCNAME(chain []string) bool {
+	for _, hop := range chain {
+		hop = strings.ToLower(strings.TrimSuffix(hop, "."))
+		for _, suffix := range f.CNAME {
+			suffix = strings.ToLower(strings.Trim(suffix, "."))
+			if hop == suffix || strings.HasSuffix(hop, "."+suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Weight returns the score contributed by a match of this fingerprint
+func (f *Fingerprint) Weight(cnameMatched bool) int {
+	switch {
+	case cnameMatched:
+		return WeightCNAME
+	case f.Pattern == "":
+		return WeightHeader
+	case f.Regex:
+		return WeightRegex
+	default:
+		return WeightString
+	}
+}
+
+// Request returns the method and path of the confirmation probe
+func (c *Confirm) Request() (string, string) {
+	method := c.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	path := c.Path
+	if strings.Contains(path, "{random}") {
+		token := make([]byte, 8)
+		rand.Read(token)
+		path = strings.ReplaceAll(path, "{random}", hex.EncodeToString(token))
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return method, path
+}
+
+// Target returns the method and path of the request
+func (r *Request) Target() (string, string) {
+	method := r.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	path := r.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return strings.ToUpper(method), path
+}
+
+// String formats the request line, e.g. "POST /api/status"
+func (r *Request) String() string {
+	method, path := r.Target()
+	return method + " " + path
+}
+
+// Match checks if the probe response has the expected status and content
+func (c *Confirm) Match(statusCode int, body string) (bool, error) {
+	if c.Status != 0 && statusCode != c.Status {
+		return false, nil
+	}
+	if c.Pattern == "" {
+		return true, nil
+	}
+
+	expected := Fingerprint{Pattern: c.Pattern, Regex: c.Regex}
+	return expected.Match(body, nil)
+}
+
+// defaultFiles holds the built-in fingerprints, one YAML file per service in
+// the format of custom fingerprint files. Files are loaded in name order.
+//
+//go:embed defaults/*.yaml
+var defaultFiles embed.FS
+
 // GetDefaultFingerprints returns the built-in fingerprints
 func GetDefaultFingerprints() *Fingerprints {
-	return &Fingerprints{
-		Fingerprints: []Fingerprint{
-			// GitHub Pages
-			{
-				Service: "GitHub Pages",
-				Pattern: "There isn't a GitHub Pages site here.",
-				Notes:   "Indicates a CNAME pointing to GitHub Pages without content",
-				Regex:   false,
-			},
-			{
-				Service: "GitHub Pages",
-				Pattern: "There isn't a GitHub Pages site here",
-				Notes:   "GitHub Pages error without period",
-				Regex:   false,
-			},
-			{
-				Service: "GitHub Pages",
-				Pattern: "(?i)there isn't a github pages site",
-				Notes:   "GitHub Pages error case insensitive",
-				Regex:   true,
-			},
-			{
-				Service: "GitHub Pages/Firebase",
-				Pattern: "Site not found",
-				Notes:   "GitHub Pages or Firebase 404 title",
-				Regex:   false,
-			},
-
-			// Vercel
-			{
-				Service: "Vercel",
-				Pattern: "(?i)project not found|there isn't a vercel deployment here|no such host",
-				Notes:   "Typical message when alias points to Vercel without deployment",
-				Regex:   true,
-			},
-
-			// Netlify
-			{
-				Service: "Netlify",
-				Pattern: "No such site",
-				Notes:   "Netlify default page text",
-				Regex:   false,
-			},
-			{
-				Service: "Netlify",
-				Pattern: "There isn't a site here",
-				Notes:   "Netlify default page text variation",
-				Regex:   false,
-			},
-			{
-				Service: "Netlify",
-				Pattern: "(?i)netlify.*not found|404.*netlify",
-				Notes:   "Netlify error with reference in body",
-				Regex:   true,
-			},
-
-			// AWS S3
-			{
-				Service: "AWS S3",
-				Pattern: "NoSuchBucket",
-				Notes:   "AWS S3 XML error for non-existent bucket",
-				Regex:   false,
-			},
-			{
-				Service: "AWS S3",
-				Pattern: "The specified bucket does not exist",
-				Notes:   "AWS S3 error message",
-				Regex:   false,
-			},
-			{
-				Service: "AWS S3",
-				Pattern: "(?i)aws.*s3.*error|amazon.*s3.*not found",
-				Notes:   "AWS S3 error variations",
-				Regex:   true,
-			},
-
-			// CloudFront
-			{
-				Service: "CloudFront",
-				Pattern: "The request could not be satisfied",
-				Notes:   "CloudFront error message",
-				Regex:   false,
-			},
-			{
-				Service: "CloudFront",
-				Pattern: "(?i)cloudfront.*error|aws.*cloudfront",
-				Notes:   "CloudFront error variations",
-				Regex:   true,
-			},
-
-			// Fastly
-			{
-				Service: "Fastly",
-				Pattern: "Fastly error: unknown domain",
-				Notes:   "Fastly error for unknown domain",
-				Regex:   false,
-			},
-			{
-				Service: "Fastly",
-				Pattern: "Fastly error: unknown service",
-				Notes:   "Fastly error for unknown service",
-				Regex:   false,
-			},
-			{
-				Service: "Fastly",
-				Pattern: "Fastly has an error",
-				Notes:   "Fastly generic error",
-				Regex:   false,
-			},
-
-			// Heroku
-			{
-				Service: "Heroku",
-				Pattern: "no such app",
-				Notes:   "Heroku app not found",
-				Regex:   false,
-			},
-			{
-				Service: "Heroku",
-				Pattern: "There is no app configured at that hostname",
-				Notes:   "Heroku custom domain removed",
-				Regex:   false,
-			},
-			{
-				Service: "Heroku",
-				Pattern: "(?i)heroku.*not found|heroku.*error",
-				Notes:   "Heroku error variations",
-				Regex:   true,
-			},
-
-			// GitLab Pages
-			{
-				Service: "GitLab Pages",
-				Pattern: "The page you were looking for doesn't exist",
-				Notes:   "GitLab Pages 404 with GitLab references",
-				Regex:   false,
-			},
-			{
-				Service: "GitLab Pages",
-				Pattern: "(?i)gitlab.*pages.*not found|gitlab.*error",
-				Notes:   "GitLab Pages error variations",
-				Regex:   true,
-			},
-
-			// Azure Blob Storage
-			{
-				Service: "Azure Blob Storage",
-				Pattern: "The specified container does not exist",
-				Notes:   "Azure Blob Storage error",
-				Regex:   false,
-			},
-			{
-				Service: "Azure Blob Storage",
-				Pattern: "Server failed to authenticate the request",
-				Notes:   "Azure authentication error",
-				Regex:   false,
-			},
-			{
-				Service: "Azure Blob Storage",
-				Pattern: "(?i)azure.*storage.*error|microsoft.*azure",
-				Notes:   "Azure error variations",
-				Regex:   true,
-			},
-
-			// Firebase / GCP Hosting
-			{
-				Service: "Firebase Hosting",
-				Pattern: "Project Not Found",
-				Notes:   "Firebase project not found",
-				Regex:   false,
-			},
-			{
-				Service: "Firebase Hosting",
-				Pattern: "(?i)firebase.*hosting.*error|gcp.*hosting.*error",
-				Notes:   "Firebase/GCP hosting error variations",
-				Regex:   true,
-			},
-
-			// Surge
-			{
-				Service: "Surge",
-				Pattern: "project not found",
-				Notes:   "Surge project not found",
-				Regex:   false,
-			},
-			{
-				Service: "Surge",
-				Pattern: "(?i)surge.*error|surge.*not found",
-				Notes:   "Surge error variations",
-				Regex:   true,
-			},
-
-			// Generic patterns (moved to end to avoid interfering with specific patterns)
-			{
-				Service: "Generic",
-				Pattern: "(?i)(no such site|project not found|no such app|the specified bucket does not exist|no such host|this page is not available)",
-				Notes:   "Generic hosting service error patterns",
-				Regex:   true,
-			},
-		},
+	fp, err := loadDefaults(defaultFiles)
+	if err != nil {
+		// The files are embedded at build time, so this cannot happen in a
+		// binary that was built from a valid tree
+		panic(err)
+	}
+	return fp
+}
+
+// loadDefaults reads and merges the YAML fingerprint files of fsys. Unknown
+// keys are rejected, so a typo in a default file fails loudly instead of
+// silently dropping a field.
+func loadDefaults(fsys fs.FS) (*Fingerprints, error) {
+	files, err := fs.Glob(fsys, "defaults/*.yaml")
+	if err != nil {
+		return nil, err
 	}
+
+	merged := &Fingerprints{}
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var fp Fingerprints
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&fp); err != nil {
+			return nil, fmt.Errorf("failed to parse default fingerprints %s: %w", name, err)
+		}
+		merged.Fingerprints = append(merged.Fingerprints, fp.Fingerprints...)
+	}
+	return merged, nil
 }
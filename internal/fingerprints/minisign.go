@@ -0,0 +1,101 @@
+package fingerprints
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisign algorithm identifiers: Ed signs the data itself, ED (the default
+// of recent minisign versions) signs its BLAKE2b-512 hash
+const (
+	minisignLegacy    = "Ed"
+	minisignPrehashed = "ED"
+)
+
+// ErrBadSignature is returned when a bundle does not match its signature or
+// checksum
+var ErrBadSignature = errors.New("signature verification failed")
+
+// VerifyMinisign verifies a minisign signature of data, including its
+// trusted comment. publicKey is the base64 key line of a minisign public key,
+// or the whole public key file. Both prehashed signatures and legacy ones made
+// with minisign -l are supported.
+func VerifyMinisign(data, signature []byte, publicKey string) error {
+	keyAlg, keyID, key, err := decodeMinisignKey(publicKey)
+	if err != nil {
+		return err
+	}
+	if keyAlg != minisignLegacy {
+		return fmt.Errorf("unsupported minisign public key algorithm %q", keyAlg)
+	}
+
+	// untrusted comment, signature, trusted comment, global signature
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("malformed minisign signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	alg, sigKeyID, sig := string(sig[:2]), sig[2:10], sig[10:]
+	switch {
+	case alg != minisignLegacy && alg != minisignPrehashed:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", alg)
+	case !bytes.Equal(sigKeyID, keyID):
+		return fmt.Errorf("%w: signed with key %X, expected %X", ErrBadSignature, sigKeyID, keyID)
+	}
+	signed := data
+	if alg == minisignPrehashed {
+		sum := blake2b.Sum512(data)
+		signed = sum[:]
+	}
+	if !ed25519.Verify(key, signed, sig) {
+		return ErrBadSignature
+	}
+
+	// The trusted comment is signed together with the signature
+	trusted := strings.TrimPrefix(lines[2], "trusted comment: ")
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	if !ed25519.Verify(key, append(bytes.Clone(sig), trusted...), global) {
+		return fmt.Errorf("%w: trusted comment was altered", ErrBadSignature)
+	}
+	return nil
+}
+
+// decodeMinisignKey decodes a minisign public key into its algorithm, key ID
+// and Ed25519 key
+func decodeMinisignKey(publicKey string) (string, []byte, ed25519.PublicKey, error) {
+	line := strings.TrimSpace(publicKey)
+	if strings.HasPrefix(line, "untrusted comment:") {
+		_, line, _ = strings.Cut(line, "\n")
+		line = strings.TrimSpace(line)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize {
+		return "", nil, nil, fmt.Errorf("invalid minisign public key")
+	}
+	return string(raw[:2]), raw[2:10], ed25519.PublicKey(raw[10:]), nil
+}
+
+// VerifySHA256 checks that data has the given hex encoded SHA-256 checksum
+func VerifySHA256(data []byte, checksum string) error {
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), strings.TrimSpace(checksum)) {
+		return fmt.Errorf("%w: SHA-256 is %x, expected %s", ErrBadSignature, sum, checksum)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package fingerprints
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Signatures of "test" made by minisign with this key, legacy (-l) and
+// prehashed
+const (
+	testPublicKey = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+
+	testLegacySignature = "untrusted comment: signature from minisign secret key\n" +
+		"RWQf6LRCGA9i59SLOFxz6NxvASXDJeRtuZykwQepbDEGt87ig1BNpWaVWuNrm73YiIiJbq71Wi+dP9eKL8OC351vwIasSSbXxwA=\n" +
+		"trusted comment: timestamp:1635442742\tfile:test\n" +
+		"0YteLgV960ia80vnA/fHbvkyjl/IoP/HNOCaZfrF0CdhAlp7ok+Tpkya+VpWPX5C/Is3q8a/kEDSY7fBmmgJCg==\n"
+
+	testPrehashedSignature = "untrusted comment: signature from minisign secret key\n" +
+		"RUQf6LRCGA9i559r3g7V1qNyJDApGip8MfqcadIgT9CuhV3EMhHoN1mGTkUidF/z7SrlQgXdy8ofjb7bNJJylDOocrCo8KLzZwo=\n" +
+		"trusted comment: timestamp:1635443258\tfile:test\thashed\n" +
+		"/cj37GK60vryibFn+ftOgbCvW9NKhKYgjVpFFQUcWPAnjO23wrvVDTt7cloNC06maoBli9q6qwZDXXoaxweICQ==\n"
+)
+
+func TestVerifyMinisign(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		signature string
+		publicKey string
+		wantErr   error // nil for a valid signature
+	}{
+		{"legacy", "test", testLegacySignature, testPublicKey, nil},
+		{"prehashed", "test", testPrehashedSignature, testPublicKey, nil},
+		{"public key file", "test", testPrehashedSignature, "untrusted comment: minisign public key E7620F1842B4E81F\n" + testPublicKey + "\n", nil},
+		{"CRLF line endings", "test", strings.ReplaceAll(testPrehashedSignature, "\n", "\r\n"), testPublicKey, nil},
+		{"legacy altered data", "test!", testLegacySignature, testPublicKey, ErrBadSignature},
+		{"prehashed altered data", "test!", testPrehashedSignature, testPublicKey, ErrBadSignature},
+		{"altered trusted comment", "test", strings.Replace(testPrehashedSignature, "hashed", "hashes", 1), testPublicKey, ErrBadSignature},
+		{"other key", "test", testPrehashedSignature, "RWRQhGcHOBlzw4CoKyugkk4ioDfoxlXxC9LBx+VNhJ3w9w+cAxgvPsuo", ErrBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyMinisign([]byte(tt.data), []byte(tt.signature), tt.publicKey)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("VerifyMinisign() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyMinisign() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
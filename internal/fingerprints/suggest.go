@@ -0,0 +1,67 @@
+package fingerprints
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	scriptRe     = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)>`)
+	tagRe        = regexp.MustCompile(`(?s)<[^>]*>`)
+	titleRe      = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	whitespaceRe = regexp.MustCompile(`\s+`)
+)
+
+// Phrases that typically appear in the error page of an unclaimed resource
+var errorPhrases = []string{"not found", "doesn't exist", "does not exist", "no such", "isn't", "unknown", "error", "not available", "no app", "no site"}
+
+// Suggest builds a starter fingerprint for the service from a sample
+// response body, using its most distinctive line of text as the pattern
+func Suggest(service, body string) Fingerprint {
+	return Fingerprint{
+		Service: service,
+		Pattern: distinctiveLine(body),
+		Notes:   "TODO: describe when this page indicates a takeover",
+		Regex:   false,
+	}
+}
+
+// distinctiveLine picks the line of visible text most likely to identify the
+// page: a line mentioning an error phrase, otherwise the title, otherwise the
+// longest reasonably sized line
+func distinctiveLine(body string) string {
+	text := scriptRe.ReplaceAllString(body, "\n")
+	text = tagRe.ReplaceAllString(text, "\n")
+
+	var lines []string
+	for _, line := range strings.Split(html.UnescapeString(text), "\n") {
+		line = strings.TrimSpace(whitespaceRe.ReplaceAllString(line, " "))
+		if len(line) >= 10 && len(line) <= 120 {
+			lines = append(lines, line)
+		}
+	}
+
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		for _, phrase := range errorPhrases {
+			if strings.Contains(lower, phrase) {
+				return line
+			}
+		}
+	}
+
+	if match := titleRe.FindStringSubmatch(body); match != nil {
+		if title := strings.TrimSpace(html.UnescapeString(match[1])); title != "" {
+			return title
+		}
+	}
+
+	longest := ""
+	for _, line := range lines {
+		if len(line) > len(longest) {
+			longest = line
+		}
+	}
+	return longest
+}
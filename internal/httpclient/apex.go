@@ -0,0 +1,66 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// apexLimiter spaces requests to the same registered domain so each apex
+// sees at most the configured rate, independently of the other apexes
+type apexLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time
+}
+
+func newApexLimiter(rate int) *apexLimiter {
+	return &apexLimiter{
+		interval: time.Second / time.Duration(rate),
+		next:     make(map[string]time.Time),
+	}
+}
+
+// wait blocks until a request to the apex of rawURL may be sent
+func (l *apexLimiter) wait(ctx context.Context, rawURL string) error {
+	apex := Apex(rawURL)
+
+	// Reserve the next free slot for the apex
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[apex]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[apex] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay := time.Until(slot); delay > 0 {
+		return sleep(ctx, delay)
+	}
+	return nil
+}
+
+// Apex returns the registered domain of the URL's host, e.g. "example.co.uk"
+// for "https://a.b.example.co.uk". IP addresses and hosts without a known
+// public suffix are returned as is.
+func Apex(rawURL string) string {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if apex, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return apex
+	}
+	return host
+}
@@ -1,157 +1,794 @@
 package httpclient
 
 import (
+	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"subtake/internal/cache"
 	"subtake/internal/config"
 )
 
 // Client wraps the HTTP client with custom configuration
 type Client struct {
-	httpClient *http.Client
-	config     *config.Config
+	httpClient  *http.Client
+	http1Client *http.Client // HTTP/1.1 only, for the HTTP/2 fallback
+	config      *config.Config
+	rateLimiter *time.Ticker
+	apexLimiter *apexLimiter
+	cache       *cache.Cache
+	userAgents  []string
+	nextAgent   atomic.Uint64
+	headers     http.Header // set last, overriding every default
+
+	// dial and tlsConfig are shared by the transports and the raw reads of
+	// malformed responses
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig *tls.Config
+
+	// slots bounds the requests in flight across all goroutines with
+	// MaxConnections; nil when unbounded
+	slots chan struct{}
+
+	// Connections opened and reused, counted in debug mode
+	newConns    atomic.Int64
+	reusedConns atomic.Int64
+
+	// Requests sent, and of those the ones repeated because of an empty
+	// body or over HTTP/1.1 after an HTTP/2 protocol error
+	requests     atomic.Int64
+	emptyRetries atomic.Int64
+	fallbacks    atomic.Int64
 }
 
+// DefaultMaxIdleConnsPerHost is the default number of idle connections kept
+// per host
+const DefaultMaxIdleConnsPerHost = 10
+
+// DefaultAccept is the Accept header sent unless configured otherwise
+const DefaultAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+// EmptyBodySize is the size below which the body of a successful response is
+// considered empty with RetryEmpty, ignoring surrounding whitespace. Error
+// pages served by hosting services are larger.
+const EmptyBodySize = 16
+
 // Response holds the HTTP response data
 type Response struct {
 	StatusCode int
-	Headers    map[string]string
+	Headers    http.Header
 	Body       string
+	BodySize   int    // size of the decoded body before truncation
+	FullBody   []byte // untruncated body, only kept when configured
+	TLS        *tls.ConnectionState
 	Error      error
+	Cached     bool
+
+	// EmptyRetries is the number of attempts that were repeated because they
+	// returned an empty body
+	EmptyRetries int
+
+	// HTTP1Fallback tells that the request failed over HTTP/2 and was
+	// repeated over HTTP/1.1
+	HTTP1Fallback bool
+
+	// The request that was sent, with credentials redacted, and its timing
+	RequestHeaders http.Header
+	Proto          string
+	Started        time.Time
+	Duration       time.Duration
+
+	// Timing breaks the duration down into phases, with the Timing option
+	Timing *Timing
+}
+
+// cachedResponse is the part of a response stored in the on-disk cache
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body"`
+	FullBody   []byte      `json:"full_body,omitempty"`
 }
 
 // New creates a new HTTP client with the given configuration
-func New(cfg *config.Config) *Client {
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: cfg.Insecure,
-		},
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     30 * time.Second,
-	}
-
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   cfg.Timeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Follow up to 10 redirects
-			if len(via) >= 10 {
-				return fmt.Errorf("too many redirects")
+func New(cfg *config.Config) (*Client, error) {
+	minVersion, err := ParseTLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minimum TLS version: %w", err)
+	}
+	maxVersion, err := ParseTLSVersion(cfg.TLSMaxVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maximum TLS version: %w", err)
+	}
+	if minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+		return nil, fmt.Errorf("minimum TLS version %s is above maximum %s", cfg.TLSMinVersion, cfg.TLSMaxVersion)
+	}
+
+	for _, cookie := range cfg.Cookies {
+		if name, _, ok := strings.Cut(cookie, "="); !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid cookie %q (expected name=value)", cookie)
+		}
+	}
+
+	headers := make(http.Header)
+	for _, header := range cfg.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q (expected Name: value)", header)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	// Pinned hosts are dialed at their IP address; the URL host is still used
+	// for SNI and the Host header
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	dialContext := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			if ip, ok := cfg.Pins.Lookup(host); ok {
+				addr = net.JoinHostPort(ip, port)
 			}
-			return nil
-		},
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.Insecure,
+		MinVersion:         minVersion,
+		MaxVersion:         maxVersion,
+	}
+
+	maxIdlePerHost := cfg.MaxIdlePerHost
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	// HTTP/2 is negotiated like browsers do; the HTTP/1.1 client serves the
+	// fallback for servers that announce HTTP/2 but fail to speak it
+	newClient := func(http2 bool) *http.Client {
+		transport := &http.Transport{
+			DialContext:         dialContext,
+			DisableKeepAlives:   cfg.NoKeepAlive,
+			ForceAttemptHTTP2:   http2,
+			TLSClientConfig:     tlsConfig.Clone(),
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: maxIdlePerHost,
+			IdleConnTimeout:     30 * time.Second,
+		}
+		if !http2 {
+			transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+
+		return &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				// Follow up to 10 redirects
+				if len(via) >= 10 {
+					return fmt.Errorf("too many redirects")
+				}
+				return nil
+			},
+		}
+	}
+
+	// The ticker is shared by every goroutine using this client, so the
+	// configured rate, or the delay between requests, applies globally
+	// regardless of concurrency
+	var rateLimiter *time.Ticker
+	switch {
+	case cfg.Delay > 0:
+		rateLimiter = time.NewTicker(cfg.Delay)
+	case cfg.Rate > 0:
+		interval := time.Second / time.Duration(cfg.Rate)
+		rateLimiter = time.NewTicker(interval)
+	}
+
+	var perApex *apexLimiter
+	if cfg.RatePerHost > 0 {
+		perApex = newApexLimiter(cfg.RatePerHost)
+	}
+
+	var responseCache *cache.Cache
+	if cfg.CacheDir != "" {
+		responseCache, err = cache.New(cfg.CacheDir, cfg.CacheTTL, cfg.CacheRefresh)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
+	}
+
+	var slots chan struct{}
+	if cfg.MaxConnections > 0 {
+		slots = make(chan struct{}, cfg.MaxConnections)
+	}
+
+	var userAgents []string
+	if cfg.UserAgentsFile != "" {
+		userAgents, err = loadUserAgents(cfg.UserAgentsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load user agents: %w", err)
+		}
 	}
 
 	return &Client{
-		httpClient: client,
-		config:     cfg,
+		httpClient:  newClient(true),
+		http1Client: newClient(false),
+		config:      cfg,
+		rateLimiter: rateLimiter,
+		apexLimiter: perApex,
+		cache:       responseCache,
+		userAgents:  userAgents,
+		headers:     headers,
+		slots:       slots,
+		dial:        dialContext,
+		tlsConfig:   tlsConfig,
+	}, nil
+}
+
+// loadUserAgents reads one user agent per line, ignoring blank lines and #
+// comments
+func loadUserAgents(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var userAgents []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			userAgents = append(userAgents, line)
+		}
+	}
+
+	if len(userAgents) == 0 {
+		return nil, fmt.Errorf("no user agents in %s", filename)
+	}
+	return userAgents, nil
+}
+
+// userAgent returns the user agent for the next request, rotating through the
+// configured list in round-robin order
+func (c *Client) userAgent() string {
+	if len(c.userAgents) == 0 {
+		return c.config.UserAgent
+	}
+	n := c.nextAgent.Add(1) - 1
+	return c.userAgents[n%uint64(len(c.userAgents))]
+}
+
+// tlsVersions maps the accepted version names to their constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a TLS version such as "1.2". An empty string returns
+// 0, which leaves the Go default in place.
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+
+	value, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(version), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q (expected 1.0, 1.1, 1.2 or 1.3)", version)
+	}
+	return value, nil
+}
+
+// Close releases the resources held by the client
+func (c *Client) Close() {
+	if c.rateLimiter != nil {
+		c.rateLimiter.Stop()
+	}
+
+	if opened, reused := c.newConns.Load(), c.reusedConns.Load(); opened+reused > 0 {
+		slog.Debug("connection reuse", "new", opened, "reused", reused)
 	}
+	if retries := c.emptyRetries.Load(); retries > 0 {
+		slog.Info("requests retried after an empty body", "retries", retries)
+	}
+	if fallbacks := c.fallbacks.Load(); fallbacks > 0 {
+		slog.Info("requests repeated over HTTP/1.1 after an HTTP/2 error", "requests", fallbacks)
+	}
+}
+
+// Requests returns the number of requests sent so far
+func (c *Client) Requests() int64 {
+	return c.requests.Load()
 }
 
-// Get performs an HTTP GET request with retries
-func (c *Client) Get(url string) *Response {
+// ErrHostTimeout is returned when the per-host time budget is exhausted
+var ErrHostTimeout = errors.New("per-host timeout exceeded")
+
+// ErrRateLimited is returned when the target kept rate limiting the requests
+// until the retries were exhausted
+var ErrRateLimited = errors.New("rate limited by target")
+
+// Get performs an HTTP GET request with retries. The context bounds the total
+// time spent across all attempts, including backoff.
+func (c *Client) Get(ctx context.Context, url string) *Response {
+	return c.Do(ctx, http.MethodGet, url)
+}
+
+// Do performs an HTTP request with the given method and retries. Rate limited
+// responses are retried after the delay requested by the server, and with
+// RetryEmpty so are successful responses with an empty body. Successful
+// responses are served from and stored in the cache when one is configured.
+func (c *Client) Do(ctx context.Context, method, url string) *Response {
+	return c.Send(ctx, method, url, nil)
+}
+
+// Payload is the body of a request along with its content type
+type Payload struct {
+	ContentType string
+	Data        []byte
+}
+
+// Send is like Do but sends the payload, if any, as the request body
+func (c *Client) Send(ctx context.Context, method, url string, payload *Payload) *Response {
+	if c.cache == nil {
+		return c.do(ctx, method, url, payload)
+	}
+
+	key := c.cacheKey(method, url, payload)
+	var cached cachedResponse
+	if c.cache.Get(key, &cached) {
+		slog.Debug("cache hit", "url", url)
+		return &Response{
+			StatusCode: cached.StatusCode,
+			Headers:    cached.Headers,
+			Body:       cached.Body,
+			FullBody:   cached.FullBody,
+			Cached:     true,
+		}
+	}
+
+	resp := c.do(ctx, method, url, payload)
+	if resp.Error == nil {
+		err := c.cache.Put(key, cachedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Headers,
+			Body:       resp.Body,
+			FullBody:   resp.FullBody,
+		})
+		if err != nil {
+			slog.Warn("failed to cache response", "url", url, "error", err)
+		}
+	}
+
+	return resp
+}
+
+// cacheKey identifies a request in the cache: besides the method and URL it
+// hashes the payload, the headers that are sent and the IP address the host
+// is pinned to, so a change to any of them misses the cache
+func (c *Client) cacheKey(method, rawURL string, payload *Payload) string {
+	key := method + " " + rawURL
+	if payload != nil {
+		sum := sha256.Sum256(append([]byte(payload.ContentType+"\x00"), payload.Data...))
+		key += " " + hex.EncodeToString(sum[:])
+	}
+
+	hash := sha256.New()
+	headers := c.requestHeaders()
+	// A rotating User-Agent would never hit the cache
+	if len(c.userAgents) == 0 && headers.Get("User-Agent") == "" {
+		headers.Set("User-Agent", c.config.UserAgent)
+	}
+	headers.Write(hash)
+	if u, err := url.Parse(rawURL); err == nil {
+		if ip, ok := c.config.Pins.Lookup(u.Hostname()); ok {
+			fmt.Fprintf(hash, "pin: %s\n", ip)
+		}
+	}
+	return key + " " + hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *Client) do(ctx context.Context, method, url string, payload *Payload) *Response {
 	var lastErr error
+	var rateLimited, empty *Response
+	emptyRetries := 0
 
 	for attempt := 0; attempt <= c.config.TimeoutRetries; attempt++ {
 		if attempt > 0 {
-			// Wait before retry
-			time.Sleep(time.Duration(attempt) * time.Second)
+			// Wait before retry, honoring Retry-After when rate limited
+			wait := time.Duration(attempt) * time.Second
+			if rateLimited != nil {
+				if retryAfter, ok := parseRetryAfter(rateLimited.Headers.Get("Retry-After"), time.Now()); ok {
+					wait = retryAfter
+				}
+			}
+			if c.config.MaxBackoff > 0 && wait > c.config.MaxBackoff {
+				wait = c.config.MaxBackoff
+			}
+
+			if err := sleep(ctx, wait); err != nil {
+				return &Response{Error: hostTimeoutError(err)}
+			}
 		}
 
-		resp, err := c.doRequest(url)
+		resp, err := c.doRequest(ctx, method, url, payload)
 		if err != nil {
+			if ctx.Err() != nil {
+				return &Response{Error: hostTimeoutError(ctx.Err())}
+			}
 			lastErr = err
+			rateLimited = nil
+
+			// Errors that cannot go away on retry are returned immediately
+			if !isRetryable(err) {
+				return &Response{Error: fmt.Errorf("request failed: %w", err)}
+			}
 			continue
 		}
 
+		if isRateLimited(resp) {
+			lastErr = ErrRateLimited
+			rateLimited = resp
+			continue
+		}
+
+		// Flaky edges intermittently answer with an empty body; the retry
+		// budget is spent on them before accepting the empty response
+		if c.config.RetryEmpty && isEmptyBody(resp) && attempt < c.config.TimeoutRetries {
+			slog.Debug("retrying empty response", "url", url, "attempt", attempt+1)
+			c.emptyRetries.Add(1)
+			emptyRetries++
+			empty = resp
+			rateLimited = nil
+			continue
+		}
+
+		resp.EmptyRetries = emptyRetries
 		return resp
 	}
 
+	// An empty response beats failing attempts after it
+	if empty != nil {
+		empty.EmptyRetries = emptyRetries
+		return empty
+	}
+
+	if rateLimited != nil {
+		rateLimited.Error = fmt.Errorf("%w after %d attempts", ErrRateLimited, c.config.TimeoutRetries+1)
+		return rateLimited
+	}
+
 	return &Response{
 		Error: fmt.Errorf("request failed after %d attempts: %w", c.config.TimeoutRetries+1, lastErr),
 	}
 }
 
-func (c *Client) doRequest(url string) (*Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// isEmptyBody reports whether resp is a successful response whose body is
+// smaller than EmptyBodySize
+func isEmptyBody(resp *Response) bool {
+	return resp.StatusCode >= 200 && resp.StatusCode < 300 && len(strings.TrimSpace(resp.Body)) < EmptyBodySize
+}
+
+// isRetryable reports whether a request error may be transient. A host that
+// does not resolve or a certificate that fails verification will not change
+// on retry, while timeouts and connection resets may.
+func isRetryable(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false
+	}
+
+	var malformed *MalformedError
+	if errors.As(err, &malformed) {
+		return false
+	}
+
+	return true
+}
+
+// isRateLimited reports whether the response asks the client to back off: a
+// 429, or a 503 carrying Retry-After. A bare 503 is kept as a regular
+// response since some services serve their takeover page with it.
+func isRateLimited(resp *Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Headers.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After value given either in seconds or as an
+// HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// hostTimeoutError maps an expired context to ErrHostTimeout
+func hostTimeoutError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrHostTimeout
+	}
+	return err
+}
+
+// requestHeaders returns the headers sent with every request apart from the
+// User-Agent, which may rotate. Headers given on the command line win over
+// the defaults, User-Agent included.
+func (c *Client) requestHeaders() http.Header {
+	headers := make(http.Header)
+	accept := c.config.Accept
+	if accept == "" {
+		accept = DefaultAccept
+	}
+	headers.Set("Accept", accept)
+	headers.Set("Accept-Language", "en-US,en;q=0.5")
+	headers.Set("Accept-Encoding", "gzip, deflate")
+	if c.config.NoKeepAlive {
+		headers.Set("Connection", "close")
+	} else {
+		headers.Set("Connection", "keep-alive")
+	}
+	headers.Set("Upgrade-Insecure-Requests", "1")
+
+	// Credentials are only sent, never recorded in results
+	if len(c.config.Cookies) > 0 {
+		headers.Set("Cookie", strings.Join(c.config.Cookies, "; "))
+	}
+	if c.config.BearerToken != "" {
+		headers.Set("Authorization", "Bearer "+c.config.BearerToken)
+	}
+
+	for name, values := range c.headers {
+		headers[name] = values
+	}
+	return headers
+}
+
+func (c *Client) doRequest(ctx context.Context, method, url string, payload *Payload) (*Response, error) {
+	if c.apexLimiter != nil {
+		if err := c.apexLimiter.wait(ctx, url); err != nil {
+			return nil, err
+		}
+	}
+	if c.rateLimiter != nil {
+		select {
+		case <-c.rateLimiter.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// A request holds its slot through redirects and the HTTP/1.1 fallback,
+	// which reuse or replace its connection rather than adding one
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+			defer func() { <-c.slots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload.Data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	if payload != nil && payload.ContentType != "" {
+		req.Header.Set("Content-Type", payload.ContentType)
+	}
+
+	req.Header.Set("User-Agent", c.userAgent())
+	for name, values := range c.requestHeaders() {
+		req.Header[name] = values
+	}
 
-	req.Header.Set("User-Agent", c.config.UserAgent)
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
-	req.Header.Set("Accept-Encoding", "gzip, deflate")
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Upgrade-Insecure-Requests", "1")
+	// Count reused connections only when they can be logged
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if info.Reused {
+					c.reusedConns.Add(1)
+				} else {
+					c.newConns.Add(1)
+				}
+			},
+		}))
+	}
+	var phases *timingTrace
+	if c.config.Timing {
+		phases = &timingTrace{}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), phases.trace()))
+	}
 
-	resp, err := c.httpClient.Do(req)
+	started := time.Now()
+	resp, data, err := c.send(c.httpClient, req)
+	fallback := false
+	if err != nil && c.config.HTTPFallback && isHTTP2Error(err) {
+		slog.Debug("HTTP/2 request failed, retrying over HTTP/1.1", "url", url, "error", err)
+		c.fallbacks.Add(1)
+		fallback = true
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			// The first attempt consumed the body
+			retry.Body, _ = req.GetBody()
+		}
+		if phases != nil {
+			// Time the repeated request only
+			phases = &timingTrace{}
+			retry = retry.WithContext(httptrace.WithClientTrace(retry.Context(), phases.trace()))
+		}
+		resp, data, err = c.send(c.http1Client, retry)
+	}
+	if err != nil && isMalformed(err) {
+		return nil, c.malformed(ctx, req, payload, err)
+	}
 	if err != nil {
 		return nil, err
 	}
+
+	response := &Response{
+		StatusCode:     resp.StatusCode,
+		Headers:        resp.Header,
+		Body:           truncateBody(data),
+		BodySize:       len(data),
+		TLS:            resp.TLS,
+		RequestHeaders: redactedHeaders(req.Header),
+		Proto:          resp.Proto,
+		Started:        started,
+		Duration:       time.Since(started),
+		HTTP1Fallback:  fallback,
+	}
+
+	if c.config.KeepFullBody {
+		response.FullBody = data
+	}
+	if phases != nil {
+		response.Timing = phases.timing()
+	}
+
+	return response, nil
+}
+
+// send sends the request with the given client and reads the response body,
+// limited to its first and last 8KB
+func (c *Client) send(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
+	c.requests.Add(1)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer resp.Body.Close()
 
-	// Read body (limit to first and last 8KB as specified)
-	body, err := c.readBody(resp.Body)
+	data, err := c.readBody(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return resp, data, nil
+}
 
-	// Convert headers to map
-	headers := make(map[string]string)
-	for name, values := range resp.Header {
-		if len(values) > 0 {
-			headers[name] = values[0]
+// isHTTP2Error reports whether err is an HTTP/2 protocol failure, such as a
+// malformed frame or a stream reset, that HTTP/1.1 would not run into. The
+// HTTP/2 error types of net/http are not exported, so their messages are
+// matched.
+func isHTTP2Error(err error) bool {
+	message := err.Error()
+	for _, marker := range []string{"http2:", "stream error:", "connection error:", "PROTOCOL_ERROR", "FRAME_SIZE_ERROR", "INTERNAL_ERROR"} {
+		if strings.Contains(message, marker) {
+			return true
 		}
 	}
+	return false
+}
 
-	return &Response{
-		StatusCode: resp.StatusCode,
-		Headers:    headers,
-		Body:       body,
-	}, nil
+// redactedHeaders returns a copy of the request headers with the credential
+// values replaced
+func redactedHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for _, name := range []string{"Cookie", "Authorization"} {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[redacted]")
+		}
+	}
+	return redacted
 }
 
-func (c *Client) readBody(body io.ReadCloser) (string, error) {
+// readBody reads the entire body, decompressing it if it is gzip encoded
+func (c *Client) readBody(body io.ReadCloser) ([]byte, error) {
 	// Read the entire body first
 	allData, err := io.ReadAll(body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Check if content is gzip compressed
-	var reader io.Reader = strings.NewReader(string(allData))
 	if len(allData) >= 2 && allData[0] == 0x1f && allData[1] == 0x8b {
-		gzReader, err := gzip.NewReader(reader)
+		gzReader, err := gzip.NewReader(bytes.NewReader(allData))
 		if err != nil {
 			// If gzip decompression fails, return original data
-			return string(allData), nil
+			return allData, nil
 		}
 		defer gzReader.Close()
 
 		decompressed, err := io.ReadAll(gzReader)
 		if err != nil {
 			// If decompression fails, return original data
-			return string(allData), nil
+			return allData, nil
 		}
 		allData = decompressed
 	}
 
+	return allData, nil
+}
+
+// truncateBody keeps the first and last 8KB of large bodies for matching
+func truncateBody(allData []byte) string {
 	// If body is small enough, return it all
 	if len(allData) <= 16384 { // 16KB total (8KB + 8KB)
-		return string(allData), nil
+		return string(allData)
 	}
 
 	// Otherwise, take first 8KB + last 8KB
 	first8KB := string(allData[:8192])
 	last8KB := string(allData[len(allData)-8192:])
 
-	return first8KB + "\n... [truncated] ...\n" + last8KB, nil
+	return first8KB + "\n... [truncated] ...\n" + last8KB
 }
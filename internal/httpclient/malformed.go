@@ -0,0 +1,151 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxRawSize bounds the bytes read from a server whose response could not be
+// parsed
+const MaxRawSize = 8 << 10
+
+// MalformedError is returned when a server answered with something that is
+// not valid HTTP, e.g. an HTTP/0.9 body without a status line. Raw holds the
+// bytes the server sent to a plain repeat of the request, if any.
+type MalformedError struct {
+	Err error
+	Raw []byte
+}
+
+func (e *MalformedError) Error() string {
+	return "malformed response: " + e.Err.Error()
+}
+
+func (e *MalformedError) Unwrap() error {
+	return e.Err
+}
+
+// sensitiveHeaders are the request headers net/http drops on a redirect to
+// another host
+var sensitiveHeaders = []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"}
+
+// isMalformed reports whether err is a failure to parse the response. The
+// parse errors of net/http are not exported, so their messages are matched.
+func isMalformed(err error) bool {
+	message := err.Error()
+	for _, marker := range []string{"malformed HTTP", "malformed MIME header", "bad Content-Length", "too many transfer encodings", "unsupported transfer encoding"} {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// malformed repeats the request over a bare connection and wraps err with
+// the raw bytes the server sends back. The request may have been redirected,
+// in which case the URL that failed is repeated, without credentials if it is
+// on another host.
+func (c *Client) malformed(ctx context.Context, req *http.Request, payload *Payload, err error) error {
+	target := req.URL
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if failed, parseErr := url.Parse(urlErr.URL); parseErr == nil && failed.Host != "" {
+			target = failed
+		}
+	}
+
+	// Like net/http on redirects, credentials are not sent to another host
+	headers := req.Header
+	if !strings.EqualFold(target.Host, req.URL.Host) {
+		headers = headers.Clone()
+		for _, name := range sensitiveHeaders {
+			headers.Del(name)
+		}
+	}
+
+	raw, rawErr := c.readRaw(ctx, req.Method, target, headers, payload)
+	if rawErr != nil {
+		slog.Debug("failed to read raw response", "url", target.String(), "error", rawErr)
+	}
+	return &MalformedError{Err: err, Raw: raw}
+}
+
+// readRaw sends an HTTP/1.1 request over a new connection, TLS for https,
+// and returns the first MaxRawSize bytes of whatever the server answers
+func (c *Client) readRaw(ctx context.Context, method string, target *url.URL, headers http.Header, payload *Payload) ([]byte, error) {
+	host, port := target.Hostname(), target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	timeout := c.config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := c.dial(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if target.Scheme == "https" {
+		config := c.tlsConfig.Clone()
+		config.ServerName = host
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	var request bytes.Buffer
+	fmt.Fprintf(&request, "%s %s HTTP/1.1\r\nHost: %s\r\n", method, target.RequestURI(), target.Host)
+	for name, values := range headers {
+		// The connection is not reused and the raw bytes are kept as sent
+		if name == "Connection" || name == "Accept-Encoding" {
+			continue
+		}
+		for _, value := range values {
+			fmt.Fprintf(&request, "%s: %s\r\n", name, value)
+		}
+	}
+	if payload != nil {
+		fmt.Fprintf(&request, "Content-Length: %d\r\n", len(payload.Data))
+	}
+	request.WriteString("Connection: close\r\n\r\n")
+	if payload != nil {
+		request.Write(payload.Data)
+	}
+
+	c.requests.Add(1)
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return nil, err
+	}
+
+	// A server that keeps the connection open is cut off at the deadline;
+	// what arrived until then is kept
+	raw, err := io.ReadAll(io.LimitReader(conn, MaxRawSize))
+	if len(raw) > 0 {
+		return raw, nil
+	}
+	return nil, err
+}
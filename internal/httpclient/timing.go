@@ -0,0 +1,82 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks the duration of a request down into its phases. Phases that
+// did not happen, e.g. the DNS lookup and connect on a reused connection or
+// the TLS handshake over HTTP, are zero.
+type Timing struct {
+	DNS       time.Duration
+	Connect   time.Duration
+	TLS       time.Duration
+	FirstByte time.Duration // from sending the request to the first response byte
+}
+
+// timingTrace records the phases of a request with httptrace. The callbacks
+// of a dial may run on other goroutines, hence the lock.
+type timingTrace struct {
+	mu sync.Mutex
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+}
+
+// trace returns the client trace that records the phases, chained with the
+// trace of ctx, if any
+func (t *timingTrace) trace() *httptrace.ClientTrace {
+	record := func(at *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*at = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { record(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			// Only the first of parallel dials (IPv4 and IPv6) counts
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectDone.IsZero() {
+				t.connectDone = time.Now()
+			}
+		},
+		TLSHandshakeStart:    func() { record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { record(&t.wroteRequest) },
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+	}
+}
+
+// timing returns the durations of the recorded phases
+func (t *timingTrace) timing() *Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phase := func(start, done time.Time) time.Duration {
+		if start.IsZero() || done.IsZero() {
+			return 0
+		}
+		return done.Sub(start)
+	}
+	return &Timing{
+		DNS:       phase(t.dnsStart, t.dnsDone),
+		Connect:   phase(t.connectStart, t.connectDone),
+		TLS:       phase(t.tlsStart, t.tlsDone),
+		FirstByte: phase(t.wroteRequest, t.firstByte),
+	}
+}
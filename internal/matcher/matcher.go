@@ -0,0 +1,69 @@
+package matcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Input is the response data piped to the external matcher as JSON
+type Input struct {
+	URL        string              `json:"url"`
+	StatusCode int                 `json:"status"`
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body"`
+}
+
+// Verdict is the JSON the external matcher writes to stdout
+type Verdict struct {
+	Vulnerable bool   `json:"vulnerable"`
+	Service    string `json:"service"`
+	Notes      string `json:"notes"`
+	Weight     int    `json:"weight,omitempty"`
+}
+
+// Command runs an external program for every response and reads back its
+// verdict
+type Command struct {
+	name string
+	args []string
+}
+
+// New creates a matcher from a command line such as "python3 matcher.py"
+func New(commandLine string) (*Command, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty matcher command")
+	}
+
+	return &Command{name: fields[0], args: fields[1:]}, nil
+}
+
+// Match pipes the input to the command and decodes its verdict
+func (c *Command) Match(ctx context.Context, input *Input) (*Verdict, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, c.name, c.args...)
+	cmd.Stdin = bytes.NewReader(data)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("matcher command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var verdict Verdict
+	if err := json.Unmarshal(out, &verdict); err != nil {
+		return nil, fmt.Errorf("invalid matcher verdict: %w", err)
+	}
+
+	return &verdict, nil
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"subtake/internal/types"
+)
+
+// rateInterval is how often the request rate gauge is updated
+const rateInterval = 5 * time.Second
+
+// Metrics exposes the progress of a scan in the Prometheus format
+type Metrics struct {
+	registry *prometheus.Registry
+	requests func() int64
+
+	scanned    prometheus.Counter
+	vulnerable prometheus.Counter
+	errors     *prometheus.CounterVec
+	rate       atomic.Uint64 // requests per second, as float64 bits
+}
+
+// New creates the metrics of a scan. requests returns the number of HTTP
+// requests sent so far.
+func New(requests func() int64) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: requests,
+		scanned: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "subtake_subdomains_scanned_total",
+			Help: "Subdomains scanned.",
+		}),
+		vulnerable: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "subtake_vulnerable_total",
+			Help: "Subdomains found vulnerable.",
+		}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "subtake_errors_total",
+			Help: "Subdomains that failed with an error, by error type.",
+		}, []string{"type"}),
+	}
+
+	m.registry.MustRegister(
+		m.scanned,
+		m.vulnerable,
+		m.errors,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "subtake_requests_total",
+			Help: "HTTP requests sent.",
+		}, func() float64 { return float64(requests()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "subtake_request_rate",
+			Help: "HTTP requests sent per second over the last interval.",
+		}, m.currentRate),
+	)
+	return m
+}
+
+// Observe counts a scan result
+func (m *Metrics) Observe(result types.Result) {
+	m.scanned.Inc()
+	switch {
+	case result.Vulnerable && result.Status == "vulnerable":
+		m.vulnerable.Inc()
+	case result.Status == "error":
+		m.errors.WithLabelValues(result.ErrorType).Inc()
+	}
+}
+
+// Serve exposes the metrics on /metrics at addr, e.g. ":9090", until the
+// returned function is called
+func (m *Metrics) Serve(addr string) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("metrics endpoint failed", "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go m.trackRate(ctx)
+
+	slog.Info("serving metrics", "addr", listener.Addr().String())
+	return func() {
+		cancel()
+		server.Close()
+	}, nil
+}
+
+// trackRate updates the request rate every interval until ctx is done
+func (m *Metrics) trackRate(ctx context.Context) {
+	ticker := time.NewTicker(rateInterval)
+	defer ticker.Stop()
+
+	last := m.requests()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := m.requests()
+			m.setRate(float64(current-last) / rateInterval.Seconds())
+			last = current
+		}
+	}
+}
+
+func (m *Metrics) setRate(rate float64) {
+	m.rate.Store(math.Float64bits(rate))
+}
+
+func (m *Metrics) currentRate() float64 {
+	return math.Float64frombits(m.rate.Load())
+}
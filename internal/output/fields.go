@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"subtake/internal/types"
+)
+
+// resultFields are the fields a result can be projected to, with the
+// function extracting each one
+var resultFields = []struct {
+	name  string
+	value func(r *types.Result) any
+}{
+	{"subdomain", func(r *types.Result) any { return r.Subdomain }},
+	{"subdomain_unicode", func(r *types.Result) any { return r.SubdomainUnicode }},
+	{"tags", func(r *types.Result) any { return r.Tags }},
+	{"vulnerable", func(r *types.Result) any { return r.Vulnerable }},
+	{"status", func(r *types.Result) any { return r.Status }},
+	{"service", func(r *types.Result) any { return r.PrimaryService() }},
+	{"score", func(r *types.Result) any { return r.Score }},
+	{"severity", func(r *types.Result) any { return r.Severity }},
+	{"confidence", func(r *types.Result) any { return Confidence(r.Score) }},
+	{"cname", func(r *types.Result) any { return r.CNAME }},
+	{"service_info", func(r *types.Result) any { return r.Service }},
+	{"service_chain", func(r *types.Result) any { return r.ServiceChain }},
+	{"evidence", func(r *types.Result) any { return r.Evidence }},
+	{"error", func(r *types.Result) any { return r.Error }},
+	{"error_type", func(r *types.Result) any { return r.ErrorType }},
+	{"ip", func(r *types.Result) any { return r.IP }},
+	{"checked_url", func(r *types.Result) any { return r.CheckedURL }},
+	{"dns_anomaly", func(r *types.Result) any { return r.DNSAnomaly }},
+	{"dns_verification", func(r *types.Result) any { return r.DNSVerification }},
+	{"cloud_ip", func(r *types.Result) any { return r.CloudIP }},
+	{"protocol_mismatch", func(r *types.Result) any { return r.ProtocolMismatch }},
+	{"slow", func(r *types.Result) any { return r.Slow }},
+	{"likely_legit", func(r *types.Result) any { return r.LikelyLegit }},
+	{"clustered_false_positive", func(r *types.Result) any { return r.ClusteredFalsePositive }},
+	{"inferred_from", func(r *types.Result) any { return r.InferredFrom }},
+	{"excluded", func(r *types.Result) any { return r.Excluded }},
+	{"http_response", func(r *types.Result) any { return r.HTTPResponse }},
+	{"https_response", func(r *types.Result) any { return r.HTTPSResponse }},
+	{"scan_time", func(r *types.Result) any { return r.ScanTime }},
+}
+
+// fieldValue returns the extractor of the named field, or nil
+func fieldValue(name string) func(r *types.Result) any {
+	for _, field := range resultFields {
+		if field.name == name {
+			return field.value
+		}
+	}
+	return nil
+}
+
+// FieldsAll selects the full result
+const FieldsAll = "all"
+
+// ParseFields parses a comma-separated list of field names. It returns nil
+// for "all" or an empty list, meaning the full result.
+func ParseFields(list string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(strings.ToLower(field))
+		switch {
+		case field == "":
+			continue
+		case field == FieldsAll:
+			return nil, nil
+		case fieldValue(field) == nil:
+			return nil, fmt.Errorf("unknown field %q (expected %s)", field, strings.Join(FieldNames(), ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// FieldNames returns the names of the projectable fields
+func FieldNames() []string {
+	names := make([]string, len(resultFields))
+	for i, field := range resultFields {
+		names[i] = field.name
+	}
+	return names
+}
+
+// Projection is a result reduced to the selected fields, encoded in the order
+// they were requested
+type Projection struct {
+	result *types.Result
+	fields []string
+}
+
+// Project returns the result reduced to the fields, or the result itself
+// when no fields are selected
+func Project(result types.Result, fields []string) any {
+	if len(fields) == 0 {
+		return result
+	}
+	return Projection{result: &result, fields: fields}
+}
+
+// MarshalJSON encodes the selected fields as an object
+func (p Projection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range p.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		value, err := json.Marshal(fieldValue(field)(p.result))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "%q:", field)
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"subtake/internal/types"
+)
+
+func TestFieldNamesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, name := range FieldNames() {
+		if seen[name] {
+			t.Errorf("field %q is listed more than once", name)
+		}
+		seen[name] = true
+		if fieldValue(name) == nil {
+			t.Errorf("field %q has no extractor", name)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{list: "", want: nil},
+		{list: "all", want: nil},
+		{list: "subdomain,all", want: nil},
+		{list: "subdomain,status", want: []string{"subdomain", "status"}},
+		{list: " Subdomain , SCORE ,", want: []string{"subdomain", "score"}},
+		{list: "service,service_info", want: []string{"service", "service_info"}},
+		{list: "subdomain,bogus", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFields(tt.list)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFields(%q) = %v, want an error", tt.list, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFields(%q) failed: %v", tt.list, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFields(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	result := types.Result{
+		Subdomain:  "assets.example.com",
+		Vulnerable: true,
+		Status:     "vulnerable",
+		Score:      15,
+		Evidence: []types.Evidence{
+			{Service: "Generic", Weight: 2},
+			{Service: "AWS S3", Weight: 10},
+		},
+		Service: &types.ServiceInfo{Name: "AWS S3", Claimable: true},
+	}
+
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{
+			fields: []string{"subdomain", "status", "score"},
+			want:   `{"subdomain":"assets.example.com","status":"vulnerable","score":15}`,
+		},
+		{
+			// Encoded in the requested order
+			fields: []string{"score", "subdomain"},
+			want:   `{"score":15,"subdomain":"assets.example.com"}`,
+		},
+		{
+			// service is the service of the heaviest evidence, service_info
+			// the registry entry
+			fields: []string{"service", "service_info"},
+			want:   `{"service":"AWS S3","service_info":{"name":"AWS S3","claimable":true}}`,
+		},
+		{
+			fields: []string{"confidence"},
+			want:   `{"confidence":"high"}`,
+		},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(Project(result, tt.fields))
+		if err != nil {
+			t.Errorf("Project(%v) failed: %v", tt.fields, err)
+			continue
+		}
+		if string(data) != tt.want {
+			t.Errorf("Project(%v) = %s, want %s", tt.fields, data, tt.want)
+		}
+	}
+
+	if got, ok := Project(result, nil).(types.Result); !ok || got.Subdomain != result.Subdomain {
+		t.Errorf("Project without fields = %v, want the full result", got)
+	}
+}
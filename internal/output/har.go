@@ -0,0 +1,181 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"subtake/internal/types"
+)
+
+// harEntry is a request/response pair in HAR 1.2 format
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string   `json:"method"`
+	URL         string   `json:"url"`
+	HTTPVersion string   `json:"httpVersion"`
+	Cookies     []harNVP `json:"cookies"`
+	Headers     []harNVP `json:"headers"`
+	QueryString []harNVP `json:"queryString"`
+	HeadersSize int      `json:"headersSize"`
+	BodySize    int      `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harNVP   `json:"cookies"`
+	Headers     []harNVP   `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harNVP is a HAR name/value pair
+type harNVP struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARWriter streams the requests and responses of a scan as the entries of a
+// HAR 1.2 archive, which browser devtools and forensic tools can load
+type HARWriter struct {
+	w       io.Writer
+	creator string
+	count   int
+}
+
+// NewHARWriter creates a writer for a HAR archive on w; version is recorded
+// as the version of the creator
+func NewHARWriter(w io.Writer, version string) *HARWriter {
+	return &HARWriter{w: w, creator: version}
+}
+
+// Write appends an entry for every recorded exchange of the result. Cached
+// responses are skipped since no request was sent for them.
+func (h *HARWriter) Write(result types.Result) error {
+	for _, resp := range result.Responses() {
+		if resp.Request == nil {
+			continue
+		}
+
+		data, err := json.MarshalIndent(harEntryFor(result.Subdomain, resp), "      ", "  ")
+		if err != nil {
+			return err
+		}
+
+		separator := ",\n      "
+		if h.count == 0 {
+			separator = h.header() + "      "
+		}
+		h.count++
+
+		if _, err := fmt.Fprintf(h.w, "%s%s", separator, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close terminates the archive. It does not close the underlying writer.
+func (h *HARWriter) Close() error {
+	if h.count == 0 {
+		_, err := io.WriteString(h.w, h.header()+"    ]\n  }\n}\n")
+		return err
+	}
+	_, err := io.WriteString(h.w, "\n    ]\n  }\n}\n")
+	return err
+}
+
+// header opens the archive up to the entries array
+func (h *HARWriter) header() string {
+	creator, _ := json.Marshal(map[string]string{"name": "subtake", "version": h.creator})
+	return fmt.Sprintf("{\n  \"log\": {\n    \"version\": \"1.2\",\n    \"creator\": %s,\n    \"entries\": [\n", creator)
+}
+
+// harEntryFor converts a recorded exchange to a HAR entry
+func harEntryFor(subdomain string, resp *types.HTTPResponse) harEntry {
+	request := resp.Request
+	milliseconds := float64(request.Duration) / float64(time.Millisecond)
+
+	proto := request.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+
+	entry := harEntry{
+		StartedDateTime: request.Started.Format(time.RFC3339Nano),
+		Time:            milliseconds,
+		Request: harRequest{
+			Method:      request.Method,
+			URL:         resp.URL,
+			HTTPVersion: proto,
+			Cookies:     []harNVP{},
+			Headers:     harHeaders(request.Headers),
+			QueryString: []harNVP{},
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: proto,
+			Cookies:     []harNVP{},
+			Headers:     harHeaders(resp.Headers),
+			Content: harContent{
+				Size:     len(resp.Body),
+				MimeType: resp.Headers.Get("Content-Type"),
+				Text:     resp.Body,
+			},
+			RedirectURL: resp.Headers.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Send: 0, Wait: milliseconds, Receive: 0},
+		Comment: subdomain,
+		Error:   resp.Error,
+	}
+	if strings.HasSuffix(resp.Body, "... [truncated]") {
+		entry.Comment += " (body truncated)"
+	}
+	return entry
+}
+
+// harHeaders lists the headers as name/value pairs sorted by name, one pair
+// per value
+func harHeaders(headers types.Headers) []harNVP {
+	pairs := []harNVP{}
+	for _, name := range headers.Names() {
+		for _, value := range headers[name] {
+			pairs = append(pairs, harNVP{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
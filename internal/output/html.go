@@ -0,0 +1,147 @@
+package output
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"subtake/internal/types"
+)
+
+// Confidence levels of a finding, derived from its score
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Confidence maps a score to a confidence level: a CNAME-confirmed match
+// alone is medium, several independent signals are high
+func Confidence(score int) string {
+	switch {
+	case score >= 15:
+		return ConfidenceHigh
+	case score >= 10:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
+
+// htmlReport is the data rendered by the HTML template
+type htmlReport struct {
+	Generated time.Time
+	Results   []types.Result
+	Services  int
+	Counts    map[string]int
+}
+
+// WriteHTML renders the results as a self-contained HTML report with a
+// summary, a sortable table and expandable evidence
+func WriteHTML(w io.Writer, results []types.Result) error {
+	report := htmlReport{
+		Generated: time.Now(),
+		Results:   results,
+		Counts:    make(map[string]int),
+	}
+
+	services := make(map[string]bool)
+	for i := range results {
+		services[results[i].PrimaryService()] = true
+		report.Counts[Confidence(results[i].Score)]++
+	}
+	report.Services = len(services)
+
+	return htmlTemplate.Execute(w, report)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"confidence": Confidence,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>SubTake Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; margin-bottom: 1.5em; }
+.summary { display: flex; gap: 1em; margin-bottom: 1.5em; }
+.summary div { border: 1px solid #ddd; border-radius: 6px; padding: 0.8em 1.2em; }
+.summary strong { display: block; font-size: 1.6em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.5em; border-bottom: 1px solid #eee; vertical-align: top; }
+th { cursor: pointer; background: #f6f6f6; user-select: none; }
+th:hover { background: #ececec; }
+.badge { border-radius: 4px; padding: 0.1em 0.5em; color: #fff; font-size: 0.85em; }
+.high { background: #c0392b; }
+.medium { background: #e67e22; }
+.low { background: #7f8c8d; }
+pre { white-space: pre-wrap; word-break: break-all; background: #f8f8f8; padding: 0.5em; margin: 0.3em 0; }
+details summary { cursor: pointer; }
+.severity { font-weight: bold; }
+.tag { background: #eef; border-radius: 4px; padding: 0 0.4em; font-size: 0.8em; color: #446; }
+.sev-critical { color: #8e1b10; }
+.sev-high { color: #c0392b; }
+.sev-medium { color: #e67e22; }
+.sev-low { color: #7f8c8d; }
+</style>
+</head>
+<body>
+<h1>SubTake Report</h1>
+<div class="meta">Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}</div>
+<div class="summary">
+<div><strong>{{len .Results}}</strong>vulnerable subdomains</div>
+<div><strong>{{.Services}}</strong>services</div>
+<div><strong>{{index .Counts "high"}}</strong><span class="badge high">high</span></div>
+<div><strong>{{index .Counts "medium"}}</strong><span class="badge medium">medium</span></div>
+<div><strong>{{index .Counts "low"}}</strong><span class="badge low">low</span></div>
+</div>
+<table id="results">
+<thead>
+<tr><th>Subdomain</th><th>Service</th><th>Severity</th><th>Score</th><th>Confidence</th><th>CNAME</th><th>Evidence</th></tr>
+</thead>
+<tbody>
+{{- range .Results}}
+<tr>
+<td>{{.Subdomain}}{{range $key, $value := .Tags}} <span class="tag">{{$key}}={{$value}}</span>{{end}}</td>
+<td>{{.PrimaryService}}</td>
+<td><span class="severity sev-{{.Severity}}">{{.Severity}}</span></td>
+<td>{{.Score}}</td>
+<td><span class="badge {{confidence .Score}}">{{confidence .Score}}</span></td>
+<td>{{if .ServiceChain}}{{range $i, $hop := .ServiceChain}}{{if $i}} &rarr; {{end}}{{if $hop.Takeover}}<strong>{{$hop.Host}}</strong>{{else}}{{$hop.Host}}{{end}}{{with $hop.Service}} ({{.}}){{end}}{{end}}{{else}}{{range $i, $hop := .CNAME}}{{if $i}} &rarr; {{end}}{{$hop}}{{end}}{{end}}</td>
+<td>
+{{- range .Evidence}}
+<details>
+<summary>{{.Service}}: {{.Pattern}} (+{{.Weight}}{{if .Confirmed}}, confirmed{{end}})</summary>
+{{- if .Notes}}<div>{{.Notes}}</div>{{end}}
+{{- if .Snippets}}{{range .Snippets}}<pre>{{.}}</pre>{{end}}{{else if .Snippet}}<pre>{{.Snippet}}</pre>{{end}}
+</details>
+{{- end}}
+{{- if .InferredFrom}}<div>Inferred from {{.InferredFrom}}</div>{{end}}
+{{- with .Service}}<div>{{.Name}}: {{if .Claimable}}claimable{{else}}not claimable{{end}}{{if .Instructions}}. {{.Instructions}}{{end}}</div>{{end}}
+</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<script>
+document.querySelectorAll("#results th").forEach(function (th, column) {
+  var ascending = true;
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#results tbody");
+    var rows = Array.prototype.slice.call(tbody.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[column].textContent.trim(), y = b.cells[column].textContent.trim();
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+      return ascending ? cmp : -cmp;
+    });
+    ascending = !ascending;
+    rows.forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -3,8 +3,12 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"time"
+
 	"subtake/internal/types"
 )
 
@@ -36,6 +40,9 @@ func PrintResult(result types.Result) {
 
 	// Print colored status
 	fmt.Printf("%s[%s]%s %s", color, strings.ToUpper(status), ColorReset, subdomain)
+	if result.SubdomainUnicode != "" {
+		fmt.Printf(" (%s)", result.SubdomainUnicode)
+	}
 
 	// Print evidence if vulnerable
 	if result.Vulnerable && len(result.Evidence) > 0 {
@@ -86,48 +93,200 @@ func PrintJSON(results []types.Result) error {
 
 // PrintDetailed prints detailed information about a result
 func PrintDetailed(result types.Result) {
-	fmt.Printf("\n--- Detailed Results for %s ---\n", result.Subdomain)
-	fmt.Printf("Status: %s\n", result.Status)
-	fmt.Printf("Vulnerable: %t\n", result.Vulnerable)
-	fmt.Printf("Scan Time: %s\n", result.ScanTime.Format("2006-01-02 15:04:05"))
+	WriteDetailed(os.Stdout, result)
+}
+
+// WriteDetailed writes the detailed information of PrintDetailed to w
+func WriteDetailed(w io.Writer, result types.Result) {
+	fmt.Fprintf(w, "\n--- Detailed Results for %s ---\n", result.Subdomain)
+	if result.SubdomainUnicode != "" {
+		fmt.Fprintf(w, "Unicode: %s\n", result.SubdomainUnicode)
+	}
+	fmt.Fprintf(w, "Status: %s\n", result.Status)
+	fmt.Fprintf(w, "Vulnerable: %t\n", result.Vulnerable)
+	fmt.Fprintf(w, "Score: %d\n", result.Score)
+	if result.Severity != "" {
+		fmt.Fprintf(w, "Severity: %s\n", result.Severity)
+	}
+	fmt.Fprintf(w, "Scan Time: %s\n", result.ScanTime.Format("2006-01-02 15:04:05"))
+
+	if len(result.Tags) > 0 {
+		keys := make([]string, 0, len(result.Tags))
+		for key := range result.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		tags := make([]string, len(keys))
+		for i, key := range keys {
+			tags[i] = key + "=" + result.Tags[key]
+		}
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(tags, ", "))
+	}
 
 	if result.Error != "" {
-		fmt.Printf("Error: %s\n", result.Error)
+		fmt.Fprintf(w, "Error: %s\n", result.Error)
+	}
+
+	if result.InferredFrom != "" {
+		fmt.Fprintf(w, "Inferred From: %s\n", result.InferredFrom)
+	}
+
+	if len(result.CNAME) > 0 {
+		fmt.Fprintf(w, "CNAME: %s -> %s\n", result.Subdomain, strings.Join(result.CNAME, " -> "))
+	}
+
+	if hops := serviceHops(result.ServiceChain); hops != "" {
+		fmt.Fprintf(w, "Service Chain: %s\n", hops)
+	}
+
+	if result.Service != nil {
+		claimable := "claimable"
+		if !result.Service.Claimable {
+			claimable = "not claimable"
+		}
+		fmt.Fprintf(w, "Service: %s (%s)\n", result.Service.Name, claimable)
+		if result.Service.Instructions != "" {
+			fmt.Fprintf(w, "Takeover: %s\n", result.Service.Instructions)
+		}
+	}
+
+	if result.ProtocolMismatch {
+		fmt.Fprintln(w, "Protocol Mismatch: HTTP and HTTPS responses differ")
+	}
+
+	if result.Slow {
+		fmt.Fprintln(w, "Slow: a response took longer than the slow threshold")
+	}
+
+	if result.LikelyLegit {
+		fmt.Fprintln(w, "Likely Legit: content-rich 200 response, only CNAME or service specific evidence counts")
+	}
+
+	if result.ClusteredFalsePositive != "" {
+		fmt.Fprintf(w, "Clustered False Positive: %s\n", result.ClusteredFalsePositive)
+	}
+
+	if result.DNSAnomaly != "" {
+		fmt.Fprintf(w, "DNS Anomaly: %s\n", result.DNSAnomaly)
+	}
+
+	if result.CloudIP != nil {
+		fmt.Fprintf(w, "Cloud IP: %s (%s, %s)\n", result.CloudIP.IP, result.CloudIP.Provider, result.CloudIP.Range)
+	}
+
+	if verification := result.DNSVerification; verification != nil {
+		fmt.Fprintf(w, "DNS Verification (%s):\n", verification.Time.Format("2006-01-02 15:04:05"))
+		for _, record := range verification.Records {
+			fmt.Fprintf(w, "  %s %d %s %s\n", record.Name, record.TTL, record.Type, record.Value)
+		}
+		if verification.NXDOMAIN {
+			fmt.Fprintln(w, "  NXDOMAIN")
+		}
+		if verification.Error != "" {
+			fmt.Fprintf(w, "  Error: %s\n", verification.Error)
+		}
 	}
 
 	if len(result.Evidence) > 0 {
-		fmt.Println("\nEvidence:")
+		fmt.Fprintln(w, "\nEvidence:")
 		for i, evidence := range result.Evidence {
-			fmt.Printf("  %d. Service: %s\n", i+1, evidence.Service)
-			fmt.Printf("     Pattern: %s\n", evidence.Pattern)
-			fmt.Printf("     Notes: %s\n", evidence.Notes)
-			fmt.Printf("     Snippet: %s\n", evidence.Snippet)
+			fmt.Fprintf(w, "  %d. Service: %s\n", i+1, evidence.Service)
+			if evidence.FingerprintID != "" {
+				fmt.Fprintf(w, "     Fingerprint: %s\n", evidence.FingerprintID)
+			}
+			fmt.Fprintf(w, "     Pattern: %s\n", evidence.Pattern)
+			fmt.Fprintf(w, "     Notes: %s\n", evidence.Notes)
+			fmt.Fprintf(w, "     Weight: %d\n", evidence.Weight)
+			if evidence.Severity != "" {
+				fmt.Fprintf(w, "     Severity: %s\n", evidence.Severity)
+			}
+			fmt.Fprintf(w, "     Matched On: %s\n", evidence.MatchField)
+			if evidence.Request != "" {
+				fmt.Fprintf(w, "     Request: %s\n", evidence.Request)
+			}
+			if evidence.Confirmed {
+				fmt.Fprintf(w, "     Confirmed: yes (active probe)\n")
+			}
+			if len(evidence.Snippets) > 1 {
+				for j, snippet := range evidence.Snippets {
+					fmt.Fprintf(w, "     Snippet %d: %s\n", j+1, snippet)
+				}
+			} else {
+				fmt.Fprintf(w, "     Snippet: %s\n", evidence.Snippet)
+			}
 		}
 	}
 
 	if result.HTTPSResponse != nil {
-		fmt.Printf("\nHTTPS Response:\n")
-		printHTTPResponse(*result.HTTPSResponse)
+		fmt.Fprintf(w, "\nHTTPS Response:\n")
+		writeHTTPResponse(w, *result.HTTPSResponse)
 	}
 
 	if result.HTTPResponse != nil {
-		fmt.Printf("\nHTTP Response:\n")
-		printHTTPResponse(*result.HTTPResponse)
+		fmt.Fprintf(w, "\nHTTP Response:\n")
+		writeHTTPResponse(w, *result.HTTPResponse)
 	}
 }
 
-func printHTTPResponse(resp types.HTTPResponse) {
-	fmt.Printf("  URL: %s\n", resp.URL)
-	fmt.Printf("  Status Code: %d\n", resp.StatusCode)
+// serviceHops formats the service chain as "host (service) -> ...", marking
+// the takeover hop. It returns "" when no hop belongs to a known service.
+func serviceHops(chain []types.ServiceHop) string {
+	known := false
+	hops := make([]string, len(chain))
+	for i, hop := range chain {
+		hops[i] = hop.Host
+		switch {
+		case hop.Service != "" && hop.Takeover:
+			hops[i] += " (" + hop.Service + ", takeover)"
+		case hop.Service != "":
+			hops[i] += " (" + hop.Service + ")"
+		case hop.Takeover:
+			hops[i] += " (takeover)"
+		}
+		known = known || hop.Service != ""
+	}
+	if !known {
+		return ""
+	}
+	return strings.Join(hops, " -> ")
+}
+
+func writeHTTPResponse(w io.Writer, resp types.HTTPResponse) {
+	fmt.Fprintf(w, "  URL: %s\n", resp.URL)
+	fmt.Fprintf(w, "  Status Code: %d\n", resp.StatusCode)
 
 	if resp.Error != "" {
-		fmt.Printf("  Error: %s\n", resp.Error)
+		fmt.Fprintf(w, "  Error: %s\n", resp.Error)
+		if resp.Raw != "" {
+			fmt.Fprintf(w, "  Raw: %q\n", resp.Raw)
+		}
 		return
 	}
 
-	fmt.Printf("  Headers:\n")
-	for name, value := range resp.Headers {
-		fmt.Printf("    %s: %s\n", name, value)
+	if resp.TLS != nil {
+		fmt.Fprintf(w, "  TLS: %s\n", resp.TLS.Version)
+		if resp.TLS.CipherSuite != "" {
+			fmt.Fprintf(w, "    Cipher Suite: %s\n", resp.TLS.CipherSuite)
+		}
+		fmt.Fprintf(w, "    Subject: %s\n", resp.TLS.Subject)
+		fmt.Fprintf(w, "    Issuer: %s\n", resp.TLS.Issuer)
+		if len(resp.TLS.DNSNames) > 0 {
+			fmt.Fprintf(w, "    DNS Names: %s\n", strings.Join(resp.TLS.DNSNames, ", "))
+		}
+		fmt.Fprintf(w, "    Expires: %s\n", resp.TLS.NotAfter.Format("2006-01-02 15:04:05"))
+	}
+
+	if resp.Timing != nil {
+		fmt.Fprintf(w, "  Timing: DNS %.1fms, connect %.1fms, TLS %.1fms, first byte %.1fms\n",
+			resp.Timing.DNS, resp.Timing.Connect, resp.Timing.TLS, resp.Timing.FirstByte)
+	}
+
+	fmt.Fprintf(w, "  Headers:\n")
+	for _, name := range resp.Headers.Names() {
+		for _, value := range resp.Headers[name] {
+			fmt.Fprintf(w, "    %s: %s\n", name, value)
+		}
 	}
 
 	// Truncate body for display
@@ -135,5 +294,97 @@ func printHTTPResponse(resp types.HTTPResponse) {
 	if len(body) > 500 {
 		body = body[:500] + "... [truncated]"
 	}
-	fmt.Printf("  Body: %s\n", body)
+	fmt.Fprintf(w, "  Body: %s\n", body)
+}
+
+// SchemaVersion is the version of the JSON results document. It is raised
+// when fields are removed or change meaning; new fields do not change it.
+const SchemaVersion = 1
+
+// DocumentHeader describes the results document written by scan and merge
+type DocumentHeader struct {
+	SchemaVersion int       `json:"schema_version"`
+	ToolVersion   string    `json:"tool_version"`
+	ScanStartedAt time.Time `json:"scan_started_at"`
+}
+
+// Document is a results document as read back from a file
+type Document struct {
+	DocumentHeader
+	Results []types.Result `json:"results"`
+}
+
+// JSONArrayWriter streams values as the elements of an indented JSON array,
+// so results can be written as they arrive instead of being held in memory.
+// The array may be embedded in a document, after a prefix and before a
+// suffix.
+type JSONArrayWriter struct {
+	w      io.Writer
+	count  int
+	prefix string // written before the array
+	indent string // indentation of the array brackets
+	suffix string // written after the array
+}
+
+// NewJSONArrayWriter creates a writer for a bare JSON array on w
+func NewJSONArrayWriter(w io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{w: w}
+}
+
+// NewDocumentWriter creates a writer for a results document on w: the header
+// fields followed by the array of results
+func NewDocumentWriter(w io.Writer, header DocumentHeader) (*JSONArrayWriter, error) {
+	data, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// Reopen the header object to append the results
+	fields := strings.TrimSuffix(string(data), "\n}")
+	return &JSONArrayWriter{
+		w:      w,
+		prefix: fields + ",\n  \"results\": ",
+		indent: "  ",
+		suffix: "\n}",
+	}, nil
+}
+
+// Resume continues an array of which count values were already written to
+// w, e.g. by an interrupted scan
+func (a *JSONArrayWriter) Resume(count int) {
+	a.count = count
+}
+
+// Count returns the number of values written to the array
+func (a *JSONArrayWriter) Count() int {
+	return a.count
+}
+
+// Write appends a value to the array
+func (a *JSONArrayWriter) Write(v interface{}) error {
+	elementIndent := a.indent + "  "
+	data, err := json.MarshalIndent(v, elementIndent, "  ")
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n" + elementIndent
+	if a.count == 0 {
+		separator = a.prefix + "[\n" + elementIndent
+	}
+	a.count++
+
+	_, err = fmt.Fprintf(a.w, "%s%s", separator, data)
+	return err
+}
+
+// Close terminates the array and the document around it. It does not close
+// the underlying writer.
+func (a *JSONArrayWriter) Close() error {
+	if a.count == 0 {
+		_, err := io.WriteString(a.w, a.prefix+"[]"+a.suffix+"\n")
+		return err
+	}
+	_, err := io.WriteString(a.w, "\n"+a.indent+"]"+a.suffix+"\n")
+	return err
 }
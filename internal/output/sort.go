@@ -0,0 +1,114 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"subtake/internal/types"
+)
+
+// SortKeys lists the supported values for SortResults
+var SortKeys = []string{"subdomain", "service", "confidence", "status", "error_type"}
+
+// ValidSortKey reports whether key is supported by SortResults
+func ValidSortKey(key string) bool {
+	for _, k := range SortKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// SortResults sorts results in place by the given key. Ties keep their
+// original order and are then broken by subdomain.
+func SortResults(results []types.Result, key string) error {
+	var less func(a, b *types.Result) bool
+
+	switch key {
+	case "subdomain":
+		less = func(a, b *types.Result) bool { return false }
+	case "service":
+		less = func(a, b *types.Result) bool { return a.PrimaryService() < b.PrimaryService() }
+	case "confidence":
+		// Highest score first
+		less = func(a, b *types.Result) bool { return a.Score > b.Score }
+	case "status":
+		less = func(a, b *types.Result) bool { return a.Status < b.Status }
+	case "error_type":
+		less = func(a, b *types.Result) bool { return a.ErrorType < b.ErrorType }
+	default:
+		return fmt.Errorf("invalid sort key %q (expected one of %s)", key, strings.Join(SortKeys, ", "))
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Subdomain < b.Subdomain
+	})
+
+	return nil
+}
+
+// PrintGroupedByService prints the vulnerable results under a heading for
+// each service
+func PrintGroupedByService(results []types.Result) {
+	groups := make(map[string][]string)
+	var services []string
+	for _, result := range results {
+		if !result.Vulnerable {
+			continue
+		}
+
+		service := result.PrimaryService()
+		if _, exists := groups[service]; !exists {
+			services = append(services, service)
+		}
+		groups[service] = append(groups[service], result.Subdomain)
+	}
+	sort.Strings(services)
+
+	fmt.Printf("\n--- Findings by Service ---\n")
+	for _, service := range services {
+		fmt.Printf("%s%s%s (%d)\n", ColorGreen, service, ColorReset, len(groups[service]))
+		for _, subdomain := range groups[service] {
+			fmt.Printf("  %s\n", subdomain)
+		}
+	}
+}
+
+// PrintGroupedByErrorType prints the errored results under a heading for
+// each error type, along with their error message
+func PrintGroupedByErrorType(results []types.Result) {
+	groups := make(map[string][]types.Result)
+	var errorTypes []string
+	for _, result := range results {
+		if result.Status != "error" {
+			continue
+		}
+
+		errorType := result.ErrorType
+		if errorType == "" {
+			errorType = "other"
+		}
+		if _, exists := groups[errorType]; !exists {
+			errorTypes = append(errorTypes, errorType)
+		}
+		groups[errorType] = append(groups[errorType], result)
+	}
+	sort.Strings(errorTypes)
+
+	fmt.Printf("\n--- Errors by Type ---\n")
+	for _, errorType := range errorTypes {
+		fmt.Printf("%s%s%s (%d)\n", ColorYellow, errorType, ColorReset, len(groups[errorType]))
+		for _, result := range groups[errorType] {
+			fmt.Printf("  %s - %s\n", result.Subdomain, result.Error)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package output
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"subtake/internal/types"
+
+	// Pure-Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables of a results database. Every scan adds a
+// row to scan_runs; its results and their evidence refer to it by run_id, so
+// the history of a subdomain can be joined across runs.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS scan_runs (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at   TEXT NOT NULL,
+	finished_at  TEXT,
+	tool_version TEXT NOT NULL,
+	input        TEXT NOT NULL,
+	results      INTEGER NOT NULL DEFAULT 0,
+	vulnerable   INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS subdomains (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id      INTEGER NOT NULL REFERENCES scan_runs(id),
+	subdomain   TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	vulnerable  INTEGER NOT NULL,
+	service     TEXT,
+	score       INTEGER NOT NULL,
+	severity    TEXT,
+	cname       TEXT,
+	error       TEXT,
+	error_type  TEXT,
+	checked_url TEXT,
+	scan_time   TEXT NOT NULL,
+	result      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS subdomains_subdomain ON subdomains(subdomain);
+CREATE INDEX IF NOT EXISTS subdomains_run ON subdomains(run_id);
+CREATE TABLE IF NOT EXISTS evidence (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id         INTEGER NOT NULL REFERENCES scan_runs(id),
+	subdomain_id   INTEGER NOT NULL REFERENCES subdomains(id),
+	fingerprint_id TEXT,
+	service        TEXT NOT NULL,
+	severity       TEXT,
+	pattern        TEXT NOT NULL,
+	match_field    TEXT NOT NULL,
+	weight         INTEGER NOT NULL,
+	confirmed      INTEGER NOT NULL,
+	snippet        TEXT
+);
+CREATE INDEX IF NOT EXISTS evidence_subdomain ON evidence(subdomain_id);
+`
+
+// sqliteBatch is the number of results committed at once, so an interrupted
+// scan keeps what was stored up to the last batch
+const sqliteBatch = 500
+
+// SQLiteWriter stores the results of a scan run in a SQLite database,
+// appending to the runs already in it
+type SQLiteWriter struct {
+	db         *sql.DB
+	tx         *sql.Tx
+	runID      int64
+	pending    int
+	results    int
+	vulnerable int
+}
+
+// NewSQLiteWriter opens or creates the database and starts a scan run of
+// input, the list file or subdomain scanned
+func NewSQLiteWriter(filename, toolVersion, input string, started time.Time) (*SQLiteWriter, error) {
+	db, err := sql.Open("sqlite", filename+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	run, err := db.Exec(`INSERT INTO scan_runs (started_at, tool_version, input) VALUES (?, ?, ?)`,
+		started.UTC().Format(time.RFC3339Nano), toolVersion, input)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to start scan run: %w", err)
+	}
+	runID, err := run.LastInsertId()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteWriter{db: db, runID: runID}, nil
+}
+
+// RunID returns the id of the scan run in scan_runs
+func (s *SQLiteWriter) RunID() int64 {
+	return s.runID
+}
+
+// Write stores a result and its evidence
+func (s *SQLiteWriter) Write(result types.Result) error {
+	if s.tx == nil {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		s.tx = tx
+	}
+
+	full, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	var cname any
+	if len(result.CNAME) > 0 {
+		data, err := json.Marshal(result.CNAME)
+		if err != nil {
+			return err
+		}
+		cname = string(data)
+	}
+
+	row, err := s.tx.Exec(`INSERT INTO subdomains (run_id, subdomain, status, vulnerable, service, score, severity, cname, error, error_type, checked_url, scan_time, result)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.runID, result.Subdomain, result.Status, result.Vulnerable, nullable(result.PrimaryService()), result.Score,
+		nullable(result.Severity), cname, nullable(result.Error), nullable(result.ErrorType), nullable(result.CheckedURL),
+		result.ScanTime.UTC().Format(time.RFC3339Nano), string(full))
+	if err != nil {
+		return err
+	}
+	subdomainID, err := row.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, evidence := range result.Evidence {
+		_, err := s.tx.Exec(`INSERT INTO evidence (run_id, subdomain_id, fingerprint_id, service, severity, pattern, match_field, weight, confirmed, snippet)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.runID, subdomainID, nullable(evidence.FingerprintID), evidence.Service, nullable(evidence.Severity),
+			evidence.Pattern, evidence.MatchField, evidence.Weight, evidence.Confirmed, nullable(evidence.Snippet))
+		if err != nil {
+			return err
+		}
+	}
+
+	s.results++
+	if result.Vulnerable {
+		s.vulnerable++
+	}
+	s.pending++
+	if s.pending >= sqliteBatch {
+		return s.commit()
+	}
+	return nil
+}
+
+// commit commits the pending results
+func (s *SQLiteWriter) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	err := s.tx.Commit()
+	s.tx = nil
+	s.pending = 0
+	return err
+}
+
+// Finish commits the pending results and records the end of the scan run
+func (s *SQLiteWriter) Finish() error {
+	if err := s.commit(); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`UPDATE scan_runs SET finished_at = ?, results = ?, vulnerable = ? WHERE id = ?`,
+		time.Now().UTC().Format(time.RFC3339Nano), s.results, s.vulnerable, s.runID)
+	return err
+}
+
+// Close commits the pending results and closes the database. A run that was
+// not finished keeps a NULL finished_at.
+func (s *SQLiteWriter) Close() error {
+	err := s.commit()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// nullable stores an empty string as NULL
+func nullable(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Representations of binary response bodies
+const (
+	BinaryHex    = "hex"
+	BinaryBase64 = "base64"
+	BinaryOmit   = "omit"
+)
+
+// BinaryModes lists the supported values of Config.BinaryBodies
+var BinaryModes = []string{BinaryHex, BinaryBase64, BinaryOmit}
+
+const (
+	// binarySample is the number of leading bytes inspected to tell text
+	// from binary
+	binarySample = 1024
+
+	// binaryPreview is the number of leading bytes kept in a summary
+	binaryPreview = 64
+)
+
+// binaryTypes are media type prefixes that are never text
+var binaryTypes = []string{
+	"image/", "audio/", "video/", "font/",
+	"application/octet-stream", "application/zip", "application/gzip", "application/x-gzip",
+	"application/pdf", "application/x-protobuf", "application/grpc", "application/wasm",
+}
+
+// IsBinary reports whether a response body is binary data rather than text,
+// by its content type or, when that is missing or claims text, by its first
+// bytes: a NUL byte, invalid UTF-8 or more than 10% of control characters
+func IsBinary(contentType, body string) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "image/svg+xml" {
+		for _, prefix := range binaryTypes {
+			if strings.HasPrefix(mediaType, prefix) {
+				return true
+			}
+		}
+	}
+
+	sample := body
+	if len(sample) > binarySample {
+		sample = sample[:binarySample]
+	}
+
+	total, unprintable := 0, 0
+	for i, r := range sample {
+		if r == utf8.RuneError && !utf8.FullRuneInString(sample[i:]) {
+			// A character cut by the sample
+			break
+		}
+		if r == 0 {
+			return true
+		}
+		total++
+		if r == utf8.RuneError || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			unprintable++
+		}
+	}
+	return unprintable*10 > total
+}
+
+// binarySummary describes a binary body in a form that is safe for
+// terminals and JSON: its size and content type, followed by its first bytes
+// in hex or base64 unless mode is BinaryOmit
+func binarySummary(contentType, body, mode string) string {
+	if contentType == "" {
+		contentType = "unknown type"
+	}
+	summary := fmt.Sprintf("[binary body: %d bytes, %s]", len(body), contentType)
+
+	preview := body
+	if len(preview) > binaryPreview {
+		preview = preview[:binaryPreview]
+	}
+	switch mode {
+	case BinaryOmit:
+		return summary
+	case BinaryBase64:
+		summary += " base64:" + base64.StdEncoding.EncodeToString([]byte(preview))
+	default:
+		summary += " hex:" + hex.EncodeToString([]byte(preview))
+	}
+	if len(preview) < len(body) {
+		summary += "..."
+	}
+	return summary
+}
@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"subtake/internal/types"
+)
+
+// breaker is a circuit breaker per apex: once the subdomains of an apex
+// failed a number of times in a row, the rest of them are skipped instead of
+// spending the scan budget on timeouts. After the cooldown one subdomain is
+// probed again and closes the circuit if it answers.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu     sync.Mutex
+	apexes map[string]*circuit
+}
+
+// circuit is the state of the breaker for one apex
+type circuit struct {
+	failures int
+	openedAt time.Time // zero while closed
+	probing  bool      // a probe is in flight after the cooldown
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		apexes:    make(map[string]*circuit),
+	}
+}
+
+// allow reports whether a subdomain of the apex may be probed
+func (b *breaker) allow(apex string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.apexes[apex]
+	if c == nil || c.openedAt.IsZero() {
+		return true
+	}
+	if b.cooldown <= 0 || c.probing || time.Since(c.openedAt) < b.cooldown {
+		return false
+	}
+
+	// Let a single probe through once the cooldown is over
+	c.probing = true
+	return true
+}
+
+// record updates the circuit of the apex with the outcome of a probe
+func (b *breaker) record(apex string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.apexes[apex]
+	if c == nil {
+		c = &circuit{}
+		b.apexes[apex] = c
+	}
+	wasProbing := c.probing
+	c.probing = false
+
+	if !failed {
+		if !c.openedAt.IsZero() {
+			slog.Info("circuit breaker closed", "apex", apex)
+		}
+		*c = circuit{}
+		return
+	}
+
+	c.failures++
+	switch {
+	case wasProbing:
+		// The probe after the cooldown failed too, wait another cooldown
+		c.openedAt = time.Now()
+	case c.openedAt.IsZero() && c.failures >= b.threshold:
+		c.openedAt = time.Now()
+		slog.Warn("circuit breaker opened, skipping the remaining subdomains of the apex", "apex", apex,
+			"failures", c.failures, "cooldown", b.cooldown)
+	}
+}
+
+// unreachable reports whether every probe of a subdomain timed out or failed
+// to connect, which counts against its apex. It is decided from the probes
+// rather than the result, which --compare-ip can turn into a finding even
+// though nothing answered.
+func unreachable(responses []*types.HTTPResponse) bool {
+	if len(responses) == 0 {
+		return false
+	}
+	for _, resp := range responses {
+		switch errorType(resp.Error) {
+		case "timeout", "connection":
+		default:
+			return false
+		}
+	}
+	return true
+}
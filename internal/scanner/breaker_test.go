@@ -0,0 +1,46 @@
+package scanner
+
+import (
+	"testing"
+
+	"subtake/internal/types"
+)
+
+func TestUnreachable(t *testing.T) {
+	timeout := &types.HTTPResponse{Error: "context deadline exceeded"}
+	refused := &types.HTTPResponse{Error: "dial tcp 192.0.2.1:443: connect: connection refused"}
+	certificate := &types.HTTPResponse{Error: "tls: failed to verify certificate: x509: certificate has expired"}
+	answered := &types.HTTPResponse{StatusCode: 404}
+
+	tests := []struct {
+		name      string
+		responses []*types.HTTPResponse
+		want      bool
+	}{
+		{"no probes", nil, false},
+		{"every probe timed out", []*types.HTTPResponse{timeout, timeout}, true},
+		{"timeouts and refused connections", []*types.HTTPResponse{timeout, refused}, true},
+		{"one probe answered", []*types.HTTPResponse{timeout, answered}, false},
+		{"TLS error", []*types.HTTPResponse{certificate, timeout}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unreachable(tt.responses); got != tt.want {
+				t.Errorf("unreachable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerOpensOnUnreachableProbes(t *testing.T) {
+	b := newBreaker(2, 0)
+	unanswered := []*types.HTTPResponse{{Error: "context deadline exceeded"}, {Error: "context deadline exceeded"}}
+
+	// A --compare-ip finding whose probes all timed out still counts as a
+	// failure
+	b.record("example.com", unreachable(unanswered))
+	b.record("example.com", unreachable(unanswered))
+	if b.allow("example.com") {
+		t.Error("breaker allowed a probe after two unreachable subdomains")
+	}
+}
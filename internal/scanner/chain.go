@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"strings"
+
+	"subtake/internal/types"
+)
+
+// serviceChain identifies the service of every hop of the CNAME chain, from
+// the service registry or else the CNAME suffixes of the fingerprints, and
+// marks the hop the takeover is at. That is the deepest hop belonging to the
+// service of the heaviest evidence, or the end of the chain when the evidence
+// names no service of the chain, e.g. a dangling target. It returns nil for
+// subdomains without a CNAME.
+func (s *Scanner) serviceChain(result *types.Result) []types.ServiceHop {
+	if len(result.CNAME) == 0 {
+		return nil
+	}
+
+	chain := make([]types.ServiceHop, len(result.CNAME))
+	names := make([][]string, len(result.CNAME))
+	for i, host := range result.CNAME {
+		chain[i].Host = host
+		if service := s.services.Lookup([]string{host}); service != nil {
+			chain[i].Service = service.Name
+			chain[i].Claimable = service.Claimable
+			names[i] = append(names[i], service.Name)
+		}
+		for _, fingerprint := range s.fingerprints.Fingerprints {
+			if fingerprint.IsEnabled() && fingerprint.MatchCNAME([]string{host}) {
+				if chain[i].Service == "" {
+					chain[i].Service = fingerprint.Service
+				}
+				names[i] = append(names[i], fingerprint.Service)
+			}
+		}
+	}
+
+	if !result.Vulnerable {
+		return chain
+	}
+
+	primary := result.PrimaryService()
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, name := range names[i] {
+			if strings.EqualFold(name, primary) {
+				chain[i].Takeover = true
+				return chain
+			}
+		}
+	}
+	chain[len(chain)-1].Takeover = true
+	return chain
+}
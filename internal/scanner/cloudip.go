@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+
+	"subtake/internal/dns"
+	"subtake/internal/fingerprints"
+	"subtake/internal/types"
+)
+
+// compareIP looks the addresses of the subdomain up in the cloud provider
+// ranges and records the first match. An address in a provider range that
+// serves nothing is likely released and can be allocated by anyone, so it is
+// evidence even without a matching body.
+func (s *Scanner) compareIP(ctx context.Context, result *types.Result, live bool) {
+	for _, addr := range s.addresses(ctx, result.Subdomain) {
+		match := s.cloudRanges.Match(addr)
+		if match == nil {
+			continue
+		}
+
+		result.CloudIP = &types.CloudIP{
+			IP:       addr.String(),
+			Provider: match.Provider,
+			Range:    match.Prefix,
+		}
+		break
+	}
+
+	if result.CloudIP == nil || live {
+		return
+	}
+
+	slog.Info("address in cloud range without a live service", "subdomain", result.Subdomain,
+		"ip", result.CloudIP.IP, "provider", result.CloudIP.Provider)
+
+	result.Evidence = append(result.Evidence, types.Evidence{
+		Service:    result.CloudIP.Provider,
+		Severity:   fingerprints.SeverityMedium,
+		Pattern:    result.CloudIP.Range,
+		Notes:      fmt.Sprintf("%s is in %s address space but no service answers on it", result.CloudIP.IP, result.CloudIP.Provider),
+		Weight:     fingerprints.WeightCloudIP,
+		MatchField: fingerprints.FieldDNS,
+	})
+	result.Score += fingerprints.WeightCloudIP
+	result.Severity = highestSeverity(result.Evidence)
+	if result.Score >= s.config.Threshold {
+		result.Vulnerable = true
+		result.Status = "vulnerable"
+	}
+}
+
+// addresses returns the addresses the subdomain is reached at: its pinned
+// address, the address itself for IP literals, or its A and AAAA records
+func (s *Scanner) addresses(ctx context.Context, subdomain string) []netip.Addr {
+	host := subdomain
+	if h, _, err := net.SplitHostPort(subdomain); err == nil {
+		host = h
+	}
+	if ip, ok := s.config.Pins.Lookup(subdomain); ok {
+		host = ip
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}
+	}
+
+	records, err := dns.Records(ctx, host)
+	if err != nil {
+		slog.Debug("address lookup failed", "subdomain", subdomain, "error", err)
+	}
+
+	var addrs []netip.Addr
+	for _, record := range records {
+		if record.Type != "A" && record.Type != "AAAA" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(record.Value); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"subtake/internal/types"
+)
+
+// Cluster is a group of findings whose checked responses have the same
+// normalized body
+type Cluster struct {
+	Hash       string // leading bytes of the normalized body hash, in hex
+	Size       int
+	Downgraded bool
+}
+
+// TrimClusters groups the vulnerable results by the normalized body of their
+// checked response. Many hosts behind one CDN or parking service serve the
+// same page, so a cluster of at least minSize findings in which no finding
+// has strong evidence is more likely a wildcard than as many takeovers: its
+// findings are downgraded to not vulnerable with a ClusteredFalsePositive
+// note. It returns the clusters of at least minSize findings, largest first.
+func TrimClusters(results []types.Result, minSize int) []Cluster {
+	members := make(map[[32]byte][]int)
+	var hashes [][32]byte
+	for i := range results {
+		if !results[i].Vulnerable {
+			continue
+		}
+		resp := results[i].CheckedResponse()
+		if resp == nil || resp.Body == "" {
+			continue
+		}
+		hash := bodyHash(resp.Body)
+		if _, exists := members[hash]; !exists {
+			hashes = append(hashes, hash)
+		}
+		members[hash] = append(members[hash], i)
+	}
+
+	var clusters []Cluster
+	for _, hash := range hashes {
+		indexes := members[hash]
+		if len(indexes) < minSize {
+			continue
+		}
+		cluster := Cluster{Hash: hex.EncodeToString(hash[:4]), Size: len(indexes), Downgraded: true}
+		for _, i := range indexes {
+			for _, evidence := range results[i].Evidence {
+				if strongEvidence(evidence) {
+					cluster.Downgraded = false
+				}
+			}
+		}
+
+		if cluster.Downgraded {
+			note := fmt.Sprintf("same response body (%s) as %d other findings, all with generic or weak evidence only", cluster.Hash, cluster.Size-1)
+			for _, i := range indexes {
+				results[i].Vulnerable = false
+				results[i].Status = "not vulnerable"
+				results[i].ClusteredFalsePositive = note
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].Size > clusters[j].Size
+	})
+	return clusters
+}
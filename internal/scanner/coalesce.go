@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"subtake/internal/types"
+)
+
+// scanCoalesced groups subdomains by their final CNAME target and probes each
+// target once. When the probed member of a group is vulnerable, the remaining
+// members are marked vulnerable without being fetched; otherwise they are
+// probed individually. emit is called with the input position of each result.
+func (s *Scanner) scanCoalesced(ctx context.Context, subdomains []string, emit func(index int, result types.Result)) {
+	results := make([]types.Result, len(subdomains))
+
+	// Resolve every CNAME chain up front
+	chains := make([]cnameChain, len(subdomains))
+	s.runWorkers(ctx, listJobs(ctx, subdomains), func(j job) types.Result {
+		chains[j.index] = s.resolveCNAME(ctx, j.subdomain)
+		return types.Result{}
+	}, func(job, types.Result) {})
+
+	// Group by CNAME target; the first member of each group is probed
+	groups := make(map[string][]int)
+	var targets []string
+	var probe []int
+	for i, chain := range chains {
+		// Anomalous chains are probed on their own
+		if len(chain.hops) == 0 || chain.anomaly != "" {
+			probe = append(probe, i)
+			continue
+		}
+
+		target := strings.ToLower(chain.hops[len(chain.hops)-1])
+		if _, exists := groups[target]; !exists {
+			targets = append(targets, target)
+			probe = append(probe, i)
+		}
+		groups[target] = append(groups[target], i)
+	}
+
+	s.scanIndexes(ctx, subdomains, chains, probe, results, emit)
+
+	// Infer the verdict for members of vulnerable groups, probe the rest
+	var remaining []int
+	for _, target := range targets {
+		members := groups[target]
+		representative := results[members[0]]
+		if !representative.Vulnerable {
+			remaining = append(remaining, members[1:]...)
+			continue
+		}
+
+		if len(members) > 1 {
+			slog.Debug("inferring results from shared CNAME target", "subdomain", representative.Subdomain,
+				"cname", target, "inferred", len(members)-1)
+		}
+
+		for _, index := range members[1:] {
+			if ctx.Err() != nil {
+				return
+			}
+			results[index] = inferResult(representative, subdomains[index], chains[index])
+			if s.config.Verify {
+				results[index].DNSVerification = verifyDNS(ctx, subdomains[index])
+			}
+			emit(index, results[index])
+		}
+	}
+
+	sort.Ints(remaining)
+	s.scanIndexes(ctx, subdomains, chains, remaining, results, emit)
+}
+
+// scanIndexes probes the subdomains at the given indexes using their resolved
+// CNAME chains and stores the results at the same indexes
+func (s *Scanner) scanIndexes(ctx context.Context, subdomains []string, chains []cnameChain, indexes []int, results []types.Result, emit func(index int, result types.Result)) {
+	jobs := make(chan job, maxWorkers)
+	go func() {
+		defer close(jobs)
+		for _, index := range indexes {
+			select {
+			case jobs <- job{index, subdomains[index]}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s.runWorkers(ctx, jobs, func(j job) types.Result {
+		return s.probeSubdomain(ctx, j.subdomain, chains[j.index])
+	}, func(j job, result types.Result) {
+		results[j.index] = result
+		emit(j.index, result)
+	})
+}
+
+// inferResult builds the result of a subdomain from the probed result of
+// another subdomain sharing the same CNAME target
+func inferResult(representative types.Result, subdomain string, chain cnameChain) types.Result {
+	return types.Result{
+		Subdomain:    subdomain,
+		Vulnerable:   representative.Vulnerable,
+		Status:       representative.Status,
+		Evidence:     representative.Evidence,
+		Score:        representative.Score,
+		Severity:     representative.Severity,
+		CNAME:        chain.hops,
+		Service:      representative.Service,
+		InferredFrom: representative.Subdomain,
+		ScanTime:     time.Now(),
+	}
+}
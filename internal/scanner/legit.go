@@ -0,0 +1,45 @@
+package scanner
+
+import (
+	"net/http"
+	"strings"
+
+	"subtake/internal/fingerprints"
+	"subtake/internal/types"
+)
+
+// Defaults of the likely legit heuristic: a live site serves a 200 with a
+// larger body and more links than the error pages of unclaimed resources
+const (
+	DefaultLegitMinBytes = 10240
+	DefaultLegitMinLinks = 20
+)
+
+// likelyLegit reports whether a response looks like a live site rather than
+// the error page of an unclaimed resource: a 200 whose body reaches both the
+// configured size and number of links
+func (s *Scanner) likelyLegit(statusCode int, body string, size int) bool {
+	if s.config.LegitMinBytes <= 0 || statusCode != http.StatusOK || size < s.config.LegitMinBytes {
+		return false
+	}
+	return strings.Count(strings.ToLower(body), "<a ") >= s.config.LegitMinLinks
+}
+
+// genericService is the service of the fingerprints matching phrases that
+// any hosting error page, or any page at all, may contain
+const genericService = "Generic"
+
+// strongEvidence reports whether evidence is specific enough to flag a
+// likely legit site: the CNAME chain points at the service, an active probe
+// or the external matcher confirmed it, or a service specific string matched
+func strongEvidence(evidence types.Evidence) bool {
+	switch {
+	case evidence.MatchField == fingerprints.FieldCNAME,
+		evidence.MatchField == fingerprints.FieldExternal,
+		evidence.Confirmed:
+		return true
+	case evidence.MatchField == fingerprints.FieldBody:
+		return evidence.Weight >= fingerprints.WeightString && !strings.EqualFold(evidence.Service, genericService)
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"context"
+	"sort"
+
+	"subtake/internal/types"
+)
+
+// orderWindow bounds the results held back in ordered mode: no subdomain is
+// started more than this many positions after the oldest one in flight
+const orderWindow = 1024
+
+// scanOrdered is ScanStream emitting the results strictly in input order: a
+// result that completes early is held until the results of all subdomains
+// before it were emitted. The input is throttled so that the held results
+// stay within orderWindow. Results held back when the scan is cancelled
+// have completed, and are still emitted in input order, past the gaps.
+func (s *Scanner) scanOrdered(ctx context.Context, subdomains <-chan string, emit func(types.Result)) {
+	// CNAME coalescing reads the whole input before scanning, which the
+	// window would block
+	input := subdomains
+	var window chan struct{}
+	if !s.config.CoalesceByCNAME {
+		window = make(chan struct{}, orderWindow)
+		throttled := make(chan string)
+		go func() {
+			defer close(throttled)
+			for subdomain := range subdomains {
+				select {
+				case window <- struct{}{}:
+					throttled <- subdomain
+				case <-ctx.Done():
+					// Drain the input, the scan discards it anyway
+				}
+			}
+		}()
+		input = throttled
+	}
+
+	held := make(map[int]types.Result)
+	next := 0
+	s.scanStream(ctx, input, func(index int, result types.Result) {
+		held[index] = result
+		for {
+			result, ok := held[next]
+			if !ok {
+				break
+			}
+			delete(held, next)
+			next++
+			if window != nil {
+				<-window
+			}
+			emit(result)
+		}
+	})
+
+	indexes := make([]int, 0, len(held))
+	for index := range held {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		emit(held[index])
+	}
+}
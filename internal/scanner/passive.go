@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"subtake/internal/dns"
+	"subtake/internal/fingerprints"
+	"subtake/internal/types"
+)
+
+// checkPassive judges a subdomain from DNS alone, without any HTTP request.
+// A CNAME target that does not resolve is a takeover candidate, and a target
+// matching the CNAME suffixes of a known service names the service.
+func (s *Scanner) checkPassive(ctx context.Context, subdomain string, chain cnameChain) types.Result {
+	result := types.Result{
+		Subdomain:  subdomain,
+		Status:     "not vulnerable",
+		CNAME:      chain.hops,
+		Service:    s.identifyService(chain.hops),
+		DNSAnomaly: chain.anomaly,
+		ScanTime:   time.Now(),
+	}
+
+	if len(result.CNAME) == 0 {
+		slog.Info("scanned subdomain", "subdomain", subdomain, "status", result.Status, "passive", true)
+		return result
+	}
+
+	// Known services the chain points at
+	service := ""
+	severity := fingerprints.SeverityMedium
+	seen := make(map[string]bool)
+	for _, fingerprint := range s.fingerprints.Fingerprints {
+		if !fingerprint.IsEnabled() || seen[fingerprint.Service] || !fingerprint.MatchCNAME(result.CNAME) {
+			continue
+		}
+		seen[fingerprint.Service] = true
+		if service == "" {
+			service = fingerprint.Service
+			severity = fingerprint.SeverityLevel()
+		}
+
+		result.Evidence = append(result.Evidence, types.Evidence{
+			FingerprintID: fingerprint.ID,
+			Severity:      fingerprint.SeverityLevel(),
+			Service:       fingerprint.Service,
+			Pattern:       result.CNAME[len(result.CNAME)-1],
+			Notes:         "CNAME points at a known service",
+			Weight:        fingerprints.WeightServiceCNAME,
+			MatchField:    fingerprints.FieldCNAME,
+		})
+		result.Score += fingerprints.WeightServiceCNAME
+	}
+
+	// The registry knows services no fingerprint has a CNAME suffix for
+	if result.Service != nil && !seen[result.Service.Name] {
+		if service == "" {
+			service = result.Service.Name
+		}
+		result.Evidence = append(result.Evidence, types.Evidence{
+			Service:    result.Service.Name,
+			Severity:   fingerprints.SeverityMedium,
+			Pattern:    result.CNAME[len(result.CNAME)-1],
+			Notes:      "CNAME points at a known service",
+			Weight:     fingerprints.WeightServiceCNAME,
+			MatchField: fingerprints.FieldCNAME,
+		})
+		result.Score += fingerprints.WeightServiceCNAME
+	}
+
+	target := result.CNAME[len(result.CNAME)-1]
+	dangling, err := dns.IsNXDOMAIN(ctx, target)
+	if err != nil {
+		slog.Debug("CNAME target lookup failed", "subdomain", subdomain, "cname", target, "error", err)
+	}
+	if dangling {
+		if service == "" {
+			service = "Dangling CNAME"
+		}
+		result.Evidence = append(result.Evidence, types.Evidence{
+			Service:    service,
+			Severity:   severity,
+			Pattern:    target,
+			Notes:      "CNAME target does not resolve (NXDOMAIN)",
+			Weight:     fingerprints.WeightDangling,
+			MatchField: fingerprints.FieldDNS,
+		})
+		result.Score += fingerprints.WeightDangling
+	}
+
+	result.Severity = highestSeverity(result.Evidence)
+	if len(result.Evidence) > 0 && result.Score >= s.config.Threshold {
+		result.Vulnerable = true
+		result.Status = "vulnerable"
+	}
+
+	slog.Info("scanned subdomain", "subdomain", subdomain, "status", result.Status, "passive", true,
+		"cname", target, "dangling", dangling)
+	return result
+}
@@ -0,0 +1,37 @@
+package scanner
+
+import (
+	"strings"
+
+	"subtake/internal/types"
+)
+
+// Match policies, deciding which matches of a response become evidence
+const (
+	MatchAll        = "all"         // every match
+	MatchPerService = "per-service" // the heaviest match of each service
+	MatchFirst      = "first"       // the first match, skipping the rest
+)
+
+// MatchPolicies lists the supported values of Config.MatchPolicy
+var MatchPolicies = []string{MatchAll, MatchPerService, MatchFirst}
+
+// perService keeps the evidence with the highest weight for each service, in
+// the order the services were first matched; on equal weights the earlier
+// evidence wins
+func perService(evidence []types.Evidence) []types.Evidence {
+	index := make(map[string]int)
+	var kept []types.Evidence
+	for _, e := range evidence {
+		key := strings.ToLower(e.Service)
+		if i, ok := index[key]; ok {
+			if e.Weight > kept[i].Weight {
+				kept[i] = e
+			}
+			continue
+		}
+		index[key] = len(kept)
+		kept = append(kept, e)
+	}
+	return kept
+}
@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"subtake/internal/config"
+	"subtake/internal/fingerprints"
+	"subtake/internal/types"
+)
+
+func TestPerService(t *testing.T) {
+	tests := []struct {
+		name     string
+		evidence []types.Evidence
+		want     []string // fingerprint ids kept
+	}{
+		{
+			name:     "no evidence",
+			evidence: nil,
+			want:     nil,
+		},
+		{
+			name: "one match per service is kept",
+			evidence: []types.Evidence{
+				{FingerprintID: "s3", Service: "AWS S3", Weight: 5},
+				{FingerprintID: "gh", Service: "GitHub Pages", Weight: 5},
+			},
+			want: []string{"s3", "gh"},
+		},
+		{
+			name: "heavier later match replaces the lighter one in its place",
+			evidence: []types.Evidence{
+				{FingerprintID: "s3-regex", Service: "AWS S3", Weight: 2},
+				{FingerprintID: "gh", Service: "GitHub Pages", Weight: 5},
+				{FingerprintID: "s3-cname", Service: "AWS S3", Weight: 10},
+			},
+			want: []string{"s3-cname", "gh"},
+		},
+		{
+			name: "equal weights keep the earlier match",
+			evidence: []types.Evidence{
+				{FingerprintID: "first", Service: "Heroku", Weight: 5},
+				{FingerprintID: "second", Service: "Heroku", Weight: 5},
+			},
+			want: []string{"first"},
+		},
+		{
+			name: "services are compared case-insensitively",
+			evidence: []types.Evidence{
+				{FingerprintID: "lower", Service: "aws s3", Weight: 5},
+				{FingerprintID: "upper", Service: "AWS S3", Weight: 10},
+			},
+			want: []string{"upper"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, evidence := range perService(tt.evidence) {
+			got = append(got, evidence.FingerprintID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: perService kept %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPolicy(t *testing.T) {
+	fp := &fingerprints.Fingerprints{Fingerprints: []fingerprints.Fingerprint{
+		{ID: "s3-regex", Service: "AWS S3", Pattern: "bucket does not exist", Regex: true},
+		{ID: "other", Service: "Other", Pattern: "Not Found"},
+		{ID: "s3-cname", Service: "AWS S3", Pattern: "NoSuchBucket", CNAME: []string{"s3.amazonaws.com"}},
+	}}
+	resp := &types.HTTPResponse{
+		URL:        "https://assets.example.com",
+		StatusCode: 404,
+		Body:       "NoSuchBucket: The specified bucket does not exist. Not Found",
+	}
+
+	tests := []struct {
+		policy     string
+		want       []string // fingerprint ids of the evidence
+		score      int
+		vulnerable bool
+	}{
+		{policy: MatchAll, want: []string{"s3-regex", "other", "s3-cname"}, score: 17, vulnerable: true},
+		{policy: "", want: []string{"s3-regex", "other", "s3-cname"}, score: 17, vulnerable: true},
+		{policy: MatchPerService, want: []string{"s3-cname", "other"}, score: 15, vulnerable: true},
+		{policy: MatchFirst, want: []string{"s3-regex"}, score: 2, vulnerable: false},
+	}
+	for _, tt := range tests {
+		s := &Scanner{config: &config.Config{Threshold: 4, MatchPolicy: tt.policy}, fingerprints: fp}
+		result := s.checkVulnerabilities(context.Background(), types.Result{
+			Subdomain: "assets.example.com",
+			CNAME:     []string{"assets.example.com.s3.amazonaws.com"},
+		}, resp)
+
+		var got []string
+		for _, evidence := range result.Evidence {
+			got = append(got, evidence.FingerprintID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("policy %q: evidence %v, want %v", tt.policy, got, tt.want)
+		}
+		if result.Score != tt.score || result.Vulnerable != tt.vulnerable {
+			t.Errorf("policy %q: score %d, vulnerable %t; want %d, %t", tt.policy, result.Score, result.Vulnerable, tt.score, tt.vulnerable)
+		}
+	}
+}
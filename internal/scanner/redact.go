@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// redacted replaces the content matched by redaction patterns
+const redacted = "[REDACTED]"
+
+// DefaultRedactPatterns match secrets commonly found in response bodies
+var DefaultRedactPatterns = []string{
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,                          // AWS access key ID
+	`(?i)aws_secret_access_key["'\s:=]+[A-Za-z0-9/+=]{40}`,   // AWS secret access key
+	`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`, // JWT
+	`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`,                    // bearer token
+	`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`,     // email address
+}
+
+// redactor removes sensitive content from response bodies before they are
+// stored. Bodies are redacted as soon as they are read, so the stored body,
+// the evidence snippets cut from it and saved bodies never contain a match.
+type redactor struct {
+	patterns []*regexp.Regexp
+}
+
+func newRedactor(patterns []string) (*redactor, error) {
+	r := &redactor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// redact replaces every match of the patterns in s
+func (r *redactor) redact(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, redacted)
+	}
+	return s
+}
+
+// redactBytes replaces every match of the patterns in b
+func (r *redactor) redactBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	for _, re := range r.patterns {
+		b = re.ReplaceAllLiteral(b, []byte(redacted))
+	}
+	return b
+}
@@ -1,15 +1,27 @@
 package scanner
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"os"
+	"html"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"subtake/internal/cloud"
 	"subtake/internal/config"
+	"subtake/internal/dns"
 	"subtake/internal/fingerprints"
 	"subtake/internal/httpclient"
+	"subtake/internal/matcher"
+	"subtake/internal/services"
 	"subtake/internal/types"
 )
 
@@ -18,86 +30,194 @@ type Scanner struct {
 	config       *config.Config
 	fingerprints *fingerprints.Fingerprints
 	httpClient   *httpclient.Client
-	rateLimiter  *time.Ticker
+	matcher      *matcher.Command
+	services     *services.Registry
+	cloudRanges  *cloud.Ranges
+	breaker      *breaker
+	redactor     *redactor
 }
 
 // New creates a new scanner
-func New(cfg *config.Config, fp *fingerprints.Fingerprints) *Scanner {
-	client := httpclient.New(cfg)
+func New(cfg *config.Config, fp *fingerprints.Fingerprints) (*Scanner, error) {
+	client, err := httpclient.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var externalMatcher *matcher.Command
+	if cfg.MatcherCmd != "" {
+		externalMatcher, err = matcher.New(cfg.MatcherCmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	registry, err := services.Load(cfg.ServicesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges *cloud.Ranges
+	if cfg.CompareIP {
+		ranges, err = cloud.Load(cfg.CloudRangesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	var rateLimiter *time.Ticker
-	if cfg.Rate > 0 {
-		interval := time.Second / time.Duration(cfg.Rate)
-		rateLimiter = time.NewTicker(interval)
+	var apexBreaker *breaker
+	if cfg.BreakerThreshold > 0 {
+		apexBreaker = newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	}
+
+	if cfg.BinaryBodies != "" && !slices.Contains(BinaryModes, cfg.BinaryBodies) {
+		return nil, fmt.Errorf("invalid binary body mode %q (expected hex, base64 or omit)", cfg.BinaryBodies)
+	}
+	if cfg.MatchPolicy != "" && !slices.Contains(MatchPolicies, cfg.MatchPolicy) {
+		return nil, fmt.Errorf("invalid match policy %q (expected all, per-service or first)", cfg.MatchPolicy)
+	}
+
+	var bodyRedactor *redactor
+	if cfg.Redact || len(cfg.RedactPatterns) > 0 {
+		bodyRedactor, err = newRedactor(append(DefaultRedactPatterns, cfg.RedactPatterns...))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &Scanner{
 		config:       cfg,
 		fingerprints: fp,
 		httpClient:   client,
-		rateLimiter:  rateLimiter,
-	}
+		matcher:      externalMatcher,
+		services:     registry,
+		cloudRanges:  ranges,
+		breaker:      apexBreaker,
+		redactor:     bodyRedactor,
+	}, nil
 }
 
-// Scan scans a list of subdomains
+// maxWorkers is the number of subdomains scanned concurrently
+const maxWorkers = 20
+
+// job is a subdomain queued for scanning along with its input position
+type job struct {
+	index     int
+	subdomain string
+}
+
+// Scan scans a list of subdomains and returns the results in input order
 func (s *Scanner) Scan(subdomains []string) []types.Result {
 	results := make([]types.Result, len(subdomains))
 
-	if s.config.Rate > 0 {
-		// Use rate limiting
-		s.scanWithRateLimit(subdomains, results)
-	} else {
-		// Use worker pool for concurrent scanning
-		s.scanWithWorkers(subdomains, results)
-	}
+	s.scanList(context.Background(), subdomains, func(index int, result types.Result) {
+		results[index] = result
+	})
 
 	return results
 }
 
-// ScanWithRealtimeOutput scans subdomains and outputs results in real-time
-func (s *Scanner) ScanWithRealtimeOutput(subdomains []string) []types.Result {
-	results := make([]types.Result, len(subdomains))
+// ScanStream scans subdomains as they are received and calls emit with each
+// result as it completes. Only a bounded number of subdomains is in flight at
+// any time, so memory use does not grow with the size of the input. With
+// CNAME coalescing the input has to be grouped first and is read completely
+// before scanning starts.
+//
+// Cancelling the context stops the scan: in-flight probes are aborted, no
+// further results are emitted and the rest of the input is discarded.
+//
+// With Ordered the results are emitted in input order instead, see
+// scanOrdered.
+func (s *Scanner) ScanStream(ctx context.Context, subdomains <-chan string, emit func(types.Result)) {
+	if s.config.Ordered {
+		s.scanOrdered(ctx, subdomains, emit)
+		return
+	}
+	s.scanStream(ctx, subdomains, func(_ int, result types.Result) {
+		emit(result)
+	})
+}
 
-	if s.config.Rate > 0 {
-		// Use rate limiting with real-time output
-		s.scanWithRateLimitRealtime(subdomains, results)
-	} else {
-		// Use worker pool with real-time output
-		s.scanWithWorkersRealtime(subdomains, results)
+// scanStream is ScanStream calling emit with the input position of each
+// result
+func (s *Scanner) scanStream(ctx context.Context, subdomains <-chan string, emit func(index int, result types.Result)) {
+	if s.config.CoalesceByCNAME {
+		var list []string
+		for subdomain := range subdomains {
+			list = append(list, subdomain)
+		}
+		s.scanCoalesced(ctx, list, emit)
+		return
 	}
 
-	return results
-}
+	jobs := make(chan job, maxWorkers)
+	go func() {
+		index := 0
+		for subdomain := range subdomains {
+			// Keep draining the input after cancellation so the
+			// producer is not blocked
+			if ctx.Err() != nil {
+				continue
+			}
 
-func (s *Scanner) scanWithRateLimit(subdomains []string, results []types.Result) {
-	for i, subdomain := range subdomains {
-		if s.rateLimiter != nil {
-			<-s.rateLimiter.C
+			select {
+			case jobs <- job{index, subdomain}:
+			case <-ctx.Done():
+			}
+			index++
 		}
+		close(jobs)
+	}()
 
-		results[i] = s.scanSubdomain(subdomain)
+	s.runWorkers(ctx, jobs, func(j job) types.Result {
+		return s.scanSubdomain(ctx, j.subdomain)
+	}, func(j job, result types.Result) {
+		emit(j.index, result)
+	})
+}
+
+// scanList scans a list of subdomains and calls emit with the input position
+// of each result as it completes
+func (s *Scanner) scanList(ctx context.Context, subdomains []string, emit func(index int, result types.Result)) {
+	if s.config.CoalesceByCNAME {
+		s.scanCoalesced(ctx, subdomains, emit)
+		return
 	}
+
+	// Use worker pool for concurrent scanning; the HTTP client's rate limiter
+	// is shared by all workers so the global request rate is respected
+	s.runWorkers(ctx, listJobs(ctx, subdomains), func(j job) types.Result {
+		return s.scanSubdomain(ctx, j.subdomain)
+	}, func(j job, result types.Result) {
+		emit(j.index, result)
+	})
 }
 
-func (s *Scanner) scanWithRateLimitRealtime(subdomains []string, results []types.Result) {
-	for i, subdomain := range subdomains {
-		if s.rateLimiter != nil {
-			<-s.rateLimiter.C
+// listJobs queues every subdomain of the list until the context is done
+func listJobs(ctx context.Context, subdomains []string) <-chan job {
+	jobs := make(chan job, maxWorkers)
+	go func() {
+		defer close(jobs)
+		for i, subdomain := range subdomains {
+			select {
+			case jobs <- job{i, subdomain}:
+			case <-ctx.Done():
+				return
+			}
 		}
-
-		results[i] = s.scanSubdomain(subdomain)
-		// Print result immediately
-		s.printResult(results[i])
-	}
+	}()
+	return jobs
 }
 
-func (s *Scanner) scanWithWorkers(subdomains []string, results []types.Result) {
-	const maxWorkers = 20
-	subdomainChan := make(chan int, len(subdomains))
+// runWorkers runs scan for every job on the worker pool and calls emit with
+// each result as it completes. emit is never called concurrently. Channels
+// are bounded by the number of workers rather than the number of jobs. Once
+// the context is done, remaining jobs are skipped and results are dropped.
+func (s *Scanner) runWorkers(ctx context.Context, jobs <-chan job, scan func(j job) types.Result, emit func(j job, result types.Result)) {
 	resultChan := make(chan struct {
-		index  int
+		job    job
 		result types.Result
-	}, len(subdomains))
+	}, maxWorkers)
 
 	// Start workers
 	var wg sync.WaitGroup
@@ -105,22 +225,19 @@ func (s *Scanner) scanWithWorkers(subdomains []string, results []types.Result) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for index := range subdomainChan {
-				result := s.scanSubdomain(subdomains[index])
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				result := scan(j)
 				resultChan <- struct {
-					index  int
+					job    job
 					result types.Result
-				}{index, result}
+				}{j, result}
 			}
 		}()
 	}
 
-	// Send work
-	for i := range subdomains {
-		subdomainChan <- i
-	}
-	close(subdomainChan)
-
 	// Wait for completion
 	go func() {
 		wg.Wait()
@@ -129,193 +246,814 @@ func (s *Scanner) scanWithWorkers(subdomains []string, results []types.Result) {
 
 	// Collect results
 	for result := range resultChan {
-		results[result.index] = result.result
+		if ctx.Err() != nil {
+			continue
+		}
+		emit(result.job, result.result)
 	}
 }
 
-func (s *Scanner) scanWithWorkersRealtime(subdomains []string, results []types.Result) {
-	const maxWorkers = 20
-	subdomainChan := make(chan int, len(subdomains))
-	resultChan := make(chan struct {
-		index  int
-		result types.Result
-	}, len(subdomains))
+func (s *Scanner) scanSubdomain(ctx context.Context, subdomain string) types.Result {
+	return s.probeSubdomain(ctx, subdomain, s.resolveCNAME(ctx, subdomain))
+}
 
-	// Start workers
-	var wg sync.WaitGroup
-	for i := 0; i < maxWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for index := range subdomainChan {
-				result := s.scanSubdomain(subdomains[index])
-				resultChan <- struct {
-					index  int
-					result types.Result
-				}{index, result}
-			}
-		}()
+// cnameChain is the resolved CNAME chain of a subdomain along with the DNS
+// anomaly found while following it, if any
+type cnameChain struct {
+	hops    []string
+	anomaly string
+}
+
+// resolveCNAME resolves the CNAME chain of a subdomain; a failed lookup is not
+// fatal for the HTTP probes. IP addresses have no chain.
+func (s *Scanner) resolveCNAME(ctx context.Context, subdomain string) cnameChain {
+	host := subdomain
+	if h, _, err := net.SplitHostPort(subdomain); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return cnameChain{}
 	}
 
-	// Send work
-	for i := range subdomains {
-		subdomainChan <- i
+	hops, err := dns.ResolveCNAME(ctx, host, s.config.MaxCNAMEDepth)
+	chain := cnameChain{hops: hops, anomaly: dns.Anomaly(err)}
+	if chain.anomaly != "" {
+		slog.Warn("CNAME anomaly", "subdomain", subdomain, "anomaly", chain.anomaly, "chain", hops, "error", err)
+	} else if err != nil {
+		slog.Debug("CNAME lookup failed", "subdomain", subdomain, "error", err)
 	}
-	close(subdomainChan)
+	return chain
+}
 
-	// Wait for completion
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+// identifyService looks the CNAME chain up in the service registry
+func (s *Scanner) identifyService(chain []string) *types.ServiceInfo {
+	service := s.services.Lookup(chain)
+	if service == nil {
+		return nil
+	}
+	return &types.ServiceInfo{
+		Name:         service.Name,
+		Claimable:    service.Claimable,
+		Instructions: service.Instructions,
+	}
+}
 
-	// Collect results and print immediately
-	for result := range resultChan {
-		results[result.index] = result.result
-		// Print result immediately
-		s.printResult(result.result)
+// probeSubdomain checks a subdomain whose CNAME chain has already been
+// resolved and verifies the DNS records of findings when configured
+func (s *Scanner) probeSubdomain(ctx context.Context, subdomain string, chain cnameChain) types.Result {
+	var result types.Result
+
+	// Passive mode never sends HTTP requests
+	if s.config.Passive {
+		result = s.checkPassive(ctx, subdomain, chain)
+	} else {
+		result = s.probeHTTP(ctx, subdomain, chain)
 	}
+	result.ServiceChain = s.serviceChain(&result)
+	result.SubdomainUnicode = dns.ToUnicode(subdomain)
+
+	if s.config.Verify && result.Vulnerable {
+		result.DNSVerification = verifyDNS(ctx, subdomain)
+	}
+	return result
 }
 
-func (s *Scanner) scanSubdomain(subdomain string) types.Result {
+// verifyDNS records the current DNS answers for a finding
+func verifyDNS(ctx context.Context, subdomain string) *types.DNSVerification {
+	host := subdomain
+	if h, _, err := net.SplitHostPort(subdomain); err == nil {
+		host = h
+	}
+
+	verification := &types.DNSVerification{Time: time.Now()}
+	if net.ParseIP(host) != nil {
+		return verification
+	}
+
+	records, err := dns.Records(ctx, host)
+	for _, record := range records {
+		verification.Records = append(verification.Records, types.DNSRecord{
+			Name:  record.Name,
+			TTL:   record.TTL,
+			Type:  record.Type,
+			Value: record.Value,
+		})
+	}
+
+	switch {
+	case errors.Is(err, dns.ErrNXDOMAIN):
+		verification.NXDOMAIN = true
+	case err != nil:
+		verification.Error = err.Error()
+		slog.Debug("DNS verification failed", "subdomain", subdomain, "error", err)
+	}
+	return verification
+}
+
+// probeHTTP sends the HTTP probes for a subdomain and checks the responses
+func (s *Scanner) probeHTTP(ctx context.Context, subdomain string, chain cnameChain) types.Result {
 	result := types.Result{
-		Subdomain: subdomain,
-		ScanTime:  time.Now(),
+		Subdomain:  subdomain,
+		ScanTime:   time.Now(),
+		CNAME:      chain.hops,
+		Service:    s.identifyService(chain.hops),
+		DNSAnomaly: chain.anomaly,
+	}
+	if ip, ok := s.config.Pins.Lookup(subdomain); ok {
+		result.IP = ip
 	}
 
-	// Try HTTPS first, then HTTP
-	httpsResult := s.tryProtocol(subdomain, "https")
-	httpResult := s.tryProtocol(subdomain, "http")
+	// Apexes whose subdomains keep failing are skipped
+	apex := httpclient.Apex("http://" + subdomain)
+	if s.breaker != nil {
+		if !s.breaker.allow(apex) {
+			result.Status = "error"
+			result.Error = fmt.Sprintf("skipped: circuit breaker open for %s", apex)
+			result.ErrorType = "circuit_open"
+			slog.Info("scanned subdomain", "subdomain", subdomain, "status", result.Status, "error_type", result.ErrorType)
+			return result
+		}
+	}
 
-	result.HTTPSResponse = httpsResult
-	result.HTTPResponse = httpResult
+	// Bound the total time spent on this host across both protocols and retries
+	if s.config.HostTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.HostTimeout)
+		defer cancel()
+	}
 
-	// Check for vulnerabilities
-	if httpsResult != nil && httpsResult.Error == "" {
-		result = s.checkVulnerabilities(result, httpsResult)
-	} else if httpResult != nil && httpResult.Error == "" {
-		result = s.checkVulnerabilities(result, httpResult)
-	} else {
+	// Probe HTTPS, HTTP and any additional ports. The responses keep the
+	// order of the targets, HTTPS first, whatever order they complete in.
+	targets := s.probeTargets(subdomain)
+	responses := s.probeAll(ctx, targets)
+	for i, target := range targets {
+		resp := responses[i]
+		switch {
+		case target.standard && target.scheme == "https":
+			result.HTTPSResponse = resp
+		case target.standard && target.scheme == "http":
+			result.HTTPResponse = resp
+		default:
+			result.PortResponses = append(result.PortResponses, resp)
+		}
+	}
+
+	// Check for vulnerabilities. By default only the first successful
+	// response is checked; with custom ports every successful response is
+	// checked and the strongest verdict wins.
+	checked := false
+	base := result
+	for _, resp := range responses {
+		raw := resp.Error != "" && resp.Raw != "" && s.config.MatchRaw
+		if resp.Error != "" && !raw {
+			continue
+		}
+
+		candidate := s.checkVulnerabilities(ctx, base, resp)
+		if raw && len(candidate.Evidence) == 0 {
+			// A malformed response only counts when its raw bytes matched
+			continue
+		}
+		candidate.CheckedURL = resp.URL
+		if !checked || candidate.Score > result.Score {
+			result = candidate
+		}
+		checked = true
+
+		if len(s.config.Ports) == 0 {
+			break
+		}
+	}
+
+	if !checked {
 		result.Status = "error"
-		result.Error = "both HTTPS and HTTP requests failed"
-		if httpsResult != nil && httpsResult.Error != "" {
-			result.Error = httpsResult.Error
-		} else if httpResult != nil && httpResult.Error != "" {
-			result.Error = httpResult.Error
+		result.Error = "all requests failed"
+		for _, resp := range responses {
+			if resp.Error != "" {
+				result.Error = resp.Error
+				break
+			}
+		}
+	}
+
+	if s.config.CompareIP {
+		s.compareIP(ctx, &result, checked)
+	}
+
+	// Backends that were deprovisioned often answer just before timing out
+	if s.config.SlowThreshold > 0 {
+		for _, resp := range responses {
+			if resp.Error == "" && resp.Request != nil && resp.Request.Duration > s.config.SlowThreshold {
+				result.Slow = true
+				slog.Info("slow response", "url", resp.URL, "duration", resp.Request.Duration)
+			}
 		}
 	}
 
+	if s.config.DiffBodies && protocolMismatch(result.HTTPSResponse, result.HTTPResponse) {
+		result.ProtocolMismatch = true
+		slog.Info("HTTP and HTTPS responses differ", "subdomain", subdomain,
+			"https_status", result.HTTPSResponse.StatusCode, "http_status", result.HTTPResponse.StatusCode)
+	}
+
+	// Full bodies are only kept for findings
+	if !result.Vulnerable {
+		for _, resp := range responses {
+			resp.FullBody = nil
+		}
+	}
+
+	if result.Status == "error" {
+		result.ErrorType = errorType(result.Error)
+	}
+	if s.breaker != nil {
+		s.breaker.record(apex, unreachable(responses))
+	}
+
+	slog.Info("scanned subdomain", "subdomain", subdomain, "status", result.Status,
+		"duration", time.Since(result.ScanTime), "error_type", result.ErrorType)
+
 	return result
 }
 
-func (s *Scanner) tryProtocol(subdomain, protocol string) *types.HTTPResponse {
-	url := fmt.Sprintf("%s://%s", protocol, subdomain)
+// errorType classifies an error message into a coarse category for logging
+func errorType(message string) string {
+	message = strings.ToLower(message)
+	switch {
+	case message == "":
+		return ""
+	case strings.Contains(message, "rate limited"):
+		return "rate_limited"
+	case strings.Contains(message, "malformed response"):
+		return "malformed_response"
+	case strings.Contains(message, "timeout") || strings.Contains(message, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(message, "no such host") || strings.Contains(message, "server misbehaving"):
+		return "dns"
+	case strings.Contains(message, "tls") || strings.Contains(message, "x509") || strings.Contains(message, "certificate"):
+		return "tls"
+	case strings.Contains(message, "connection refused") || strings.Contains(message, "connection reset") || strings.Contains(message, "unreachable"):
+		return "connection"
+	default:
+		return "other"
+	}
+}
+
+// OperationalError reports whether an error message points at the scanning
+// host rather than the target: the resolver cannot be reached or the local
+// network is down. Target errors like NXDOMAIN, refused connections or a
+// SERVFAIL for a broken delegation are not operational.
+func OperationalError(message string) bool {
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "network is unreachable"):
+		return true
+	case strings.Contains(message, "too many open files"):
+		return true
+	case strings.Contains(message, "lookup ") && strings.Contains(message, ":53: "):
+		// The resolver itself did not answer, e.g.
+		// "lookup x on 10.0.0.2:53: read udp ...: i/o timeout"
+		return strings.Contains(message, "i/o timeout") || strings.Contains(message, "connection refused")
+	default:
+		return false
+	}
+}
 
-	resp := s.httpClient.Get(url)
+// protocolMismatch reports whether both protocols answered and their status
+// codes or normalized bodies differ
+func protocolMismatch(https, http *types.HTTPResponse) bool {
+	if https == nil || http == nil || https.Error != "" || http.Error != "" {
+		return false
+	}
+	if https.StatusCode != http.StatusCode {
+		return true
+	}
+	return bodyHash(https.Body) != bodyHash(http.Body)
+}
 
-	// Only keep essential headers
-	essentialHeaders := make(map[string]string)
-	importantHeaders := []string{"Server", "Content-Type", "Content-Length", "X-GitHub-Request-Id", "X-Served-By"}
+// bodyHash hashes a body after normalizing case, whitespace and the scheme of
+// absolute links, which routinely differ between protocols
+func bodyHash(body string) [sha256.Size]byte {
+	normalized := strings.ToLower(body)
+	normalized = strings.ReplaceAll(normalized, "https://", "http://")
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	return sha256.Sum256([]byte(normalized))
+}
 
-	for _, header := range importantHeaders {
-		if value, exists := resp.Headers[header]; exists {
-			essentialHeaders[header] = value
+// probeTarget is a URL to probe for a subdomain
+type probeTarget struct {
+	scheme   string
+	url      string
+	standard bool // default port of the scheme
+}
+
+// probeTargets returns the URLs to probe for a subdomain: HTTPS and HTTP on
+// their default ports, or one URL per configured port. Ports ending in 443
+// use HTTPS, all others HTTP. Subdomains that already carry a port are probed
+// on that port only.
+func (s *Scanner) probeTargets(subdomain string) []probeTarget {
+	_, _, err := net.SplitHostPort(subdomain)
+	if len(s.config.Ports) == 0 || err == nil {
+		return []probeTarget{
+			{scheme: "https", url: "https://" + subdomain, standard: true},
+			{scheme: "http", url: "http://" + subdomain, standard: true},
+		}
+	}
+
+	targets := make([]probeTarget, 0, len(s.config.Ports))
+	for _, port := range s.config.Ports {
+		scheme := "http"
+		if port%1000 == 443 {
+			scheme = "https"
+		}
+
+		switch {
+		case scheme == "https" && port == 443, scheme == "http" && port == 80:
+			targets = append(targets, probeTarget{scheme: scheme, url: scheme + "://" + subdomain, standard: true})
+		default:
+			targets = append(targets, probeTarget{scheme: scheme, url: fmt.Sprintf("%s://%s:%d", scheme, subdomain, port)})
 		}
 	}
+	return targets
+}
 
-	// Truncate body to first 1000 characters for storage
+// probeAll probes the targets, up to ProbeConcurrency at a time, so a
+// hanging HTTPS port does not hold back the HTTP probe. The rate limiters and
+// the per-host timeout of ctx still apply to every request.
+func (s *Scanner) probeAll(ctx context.Context, targets []probeTarget) []*types.HTTPResponse {
+	responses := make([]*types.HTTPResponse, len(targets))
+	if s.config.ProbeConcurrency <= 1 {
+		for i, target := range targets {
+			responses[i] = s.tryURL(ctx, target.url)
+		}
+		return responses
+	}
+
+	slots := make(chan struct{}, s.config.ProbeConcurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, url string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			responses[i] = s.tryURL(ctx, url)
+		}(i, target.url)
+	}
+	wg.Wait()
+	return responses
+}
+
+func (s *Scanner) tryURL(ctx context.Context, url string) *types.HTTPResponse {
+	started := time.Now()
+	resp := s.httpClient.Get(ctx, url)
+
+	// Keep every header with all of its values, e.g. repeated Set-Cookie
+	headers := make(types.Headers, len(resp.Headers))
+	for name, values := range resp.Headers {
+		headers[name] = values
+	}
+
+	// Redact before truncating, so a secret cut in half is still removed
+	if s.redactor != nil {
+		resp.Body = s.redactor.redact(resp.Body)
+		resp.FullBody = s.redactor.redactBytes(resp.FullBody)
+	}
+
+	// Binary bodies are stored as a summary that cannot corrupt terminals
+	// or JSON; text bodies are truncated to their first 1000 characters
 	body := resp.Body
-	if len(body) > 1000 {
+	binary := resp.Error == nil && IsBinary(resp.Headers.Get("Content-Type"), body)
+	switch {
+	case binary:
+		body = binarySummary(resp.Headers.Get("Content-Type"), body, s.config.BinaryBodies)
+	case len(body) > 1000:
 		body = body[:1000] + "... [truncated]"
 	}
 
+	// Cached responses do not record the size of their body
+	size := resp.BodySize
+	if size == 0 {
+		size = len(resp.Body)
+	}
+
 	httpResp := &types.HTTPResponse{
-		URL:        url,
-		StatusCode: resp.StatusCode,
-		Headers:    essentialHeaders,
-		Body:       body,
+		URL:          url,
+		StatusCode:   resp.StatusCode,
+		Headers:      headers,
+		Body:         body,
+		Binary:       binary,
+		BodySize:     size,
+		LikelyLegit:  resp.Error == nil && !binary && s.likelyLegit(resp.StatusCode, resp.Body, size),
+		FullBody:     resp.FullBody,
+		Cached:       resp.Cached,
+		EmptyRetries: resp.EmptyRetries,
+		HTTPFallback: resp.HTTP1Fallback,
 	}
 
 	if resp.Error != nil {
 		httpResp.Error = resp.Error.Error()
 	}
 
+	// What a broken server sent is kept for manual inspection
+	var malformed *httpclient.MalformedError
+	if errors.As(resp.Error, &malformed) && len(malformed.Raw) > 0 {
+		raw := strings.ToValidUTF8(string(malformed.Raw), "\uFFFD")
+		if s.redactor != nil {
+			raw = s.redactor.redact(raw)
+		}
+		if len(raw) > 1000 {
+			raw = raw[:1000] + "... [truncated]"
+		}
+		httpResp.Raw = raw
+	}
+
+	switch {
+	case resp.RequestHeaders != nil:
+		httpResp.Request = &types.HTTPRequest{
+			Method:   http.MethodGet,
+			Headers:  types.Headers(resp.RequestHeaders),
+			Proto:    resp.Proto,
+			Started:  resp.Started,
+			Duration: resp.Duration,
+		}
+	case resp.Error != nil:
+		// Failed requests are recorded with the time spent on all attempts
+		httpResp.Request = &types.HTTPRequest{
+			Method:   http.MethodGet,
+			Started:  started,
+			Duration: time.Since(started),
+		}
+	}
+
+	if resp.TLS != nil {
+		httpResp.TLS = tlsInfo(resp.TLS)
+	}
+	if resp.Timing != nil {
+		httpResp.Timing = &types.Timing{
+			DNS:       milliseconds(resp.Timing.DNS),
+			Connect:   milliseconds(resp.Timing.Connect),
+			TLS:       milliseconds(resp.Timing.TLS),
+			FirstByte: milliseconds(resp.Timing.FirstByte),
+		}
+	}
+
 	return httpResp
 }
 
-func (s *Scanner) checkVulnerabilities(result types.Result, httpResp *types.HTTPResponse) types.Result {
-	// Debug output in verbose mode
-	if s.config.Verbose {
-		fmt.Fprintf(os.Stderr, "Checking %s - Status: %d, Body length: %d\n", result.Subdomain, httpResp.StatusCode, len(httpResp.Body))
-		// Show first 1000 characters of body
-		bodyPreview := httpResp.Body
-		if len(bodyPreview) > 1000 {
-			bodyPreview = bodyPreview[:1000] + "..."
-		}
-		fmt.Fprintf(os.Stderr, "Body content: %q\n", bodyPreview)
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// tlsInfo summarizes the negotiated TLS connection and leaf certificate
+func tlsInfo(state *tls.ConnectionState) *types.TLSInfo {
+	info := &types.TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.Subject = cert.Subject.String()
+		info.Issuer = cert.Issuer.String()
+		info.DNSNames = cert.DNSNames
+		info.NotAfter = cert.NotAfter
 	}
 
-	// Check fingerprints against response body
-	matches, err := s.fingerprints.Match(httpResp.Body, httpResp.Headers)
+	return info
+}
+
+func (s *Scanner) checkVulnerabilities(ctx context.Context, result types.Result, httpResp *types.HTTPResponse) types.Result {
+	slog.Debug("checking response", "subdomain", result.Subdomain, "url", httpResp.URL,
+		"status_code", httpResp.StatusCode, "body_length", len(httpResp.Body), "body", httpResp.Body)
+
+	// Check fingerprints against response body. With FirstMatch the
+	// evaluation stops once the matches reach the threshold, with the first
+	// match policy at the first match: every match weighs at least 1.
+	stopAt := 0
+	switch {
+	case s.config.MatchPolicy == MatchFirst:
+		stopAt = 1
+	case s.config.FirstMatch:
+		stopAt = s.config.Threshold
+	}
+
+	// The summary of a binary body is not matched against, so no snippet
+	// is ever taken from it
+	body := httpResp.Body
+	switch {
+	case httpResp.Binary:
+		body = ""
+	case httpResp.Error != "":
+		// With MatchRaw, the bytes of a malformed response
+		body = httpResp.Raw
+	}
+	matches, err := s.fingerprints.MatchUntil(&fingerprints.Target{
+		StatusCode: httpResp.StatusCode,
+		Body:       body,
+		Headers:    http.Header(httpResp.Headers),
+		CNAME:      result.CNAME,
+		Service:    serviceName(result.Service),
+	}, stopAt)
 	if err != nil {
 		result.Status = "error"
 		result.Error = fmt.Sprintf("fingerprint matching error: %v", err)
 		return result
 	}
 
-	if len(matches) > 0 {
-		result.Vulnerable = true
-		result.Status = "vulnerable"
+	// Create evidence for each match; the weights add up to the score
+	for _, match := range matches {
+		evidence := types.Evidence{
+			FingerprintID: match.Fingerprint.ID,
+			Severity:      match.Fingerprint.SeverityLevel(),
+			Service:       match.Fingerprint.Service,
+			Pattern:       match.Fingerprint.Describe(),
+			Notes:         match.Fingerprint.Notes,
+			Weight:        match.Weight,
+			MatchField:    match.Field,
+		}
+		if match.Fingerprint.Pattern == "" && match.Fingerprint.Header != nil {
+			evidence.Snippet = headerSnippet(httpResp.Headers, match.Fingerprint.Header.Name)
+		} else {
+			snippets := s.extractSnippets(body, &match.Fingerprint)
+			if len(snippets) > 0 {
+				evidence.Snippet = snippets[0]
+			}
+			if s.config.AllMatches {
+				evidence.Snippets = snippets
+			}
+		}
+
+		// Raise the weight when the active probe confirms the match
+		if s.config.ActiveConfirm && match.Fingerprint.Confirm != nil && s.confirm(ctx, httpResp.URL, match.Fingerprint.Confirm) {
+			evidence.Confirmed = true
+			evidence.Weight += fingerprints.WeightConfirmed
+		}
+
+		result.Evidence = append(result.Evidence, evidence)
+		result.Score += evidence.Weight
+	}
 
-		// Create evidence for each match
-		for _, match := range matches {
-			evidence := types.Evidence{
-				Service: match.Service,
-				Pattern: match.Pattern,
-				Notes:   match.Notes,
-				Snippet: s.extractSnippet(httpResp.Body, match.Pattern),
+	// Fingerprints with their own request, e.g. a POST, are intrusive and
+	// only matched with AllowPost
+	if s.config.AllowPost {
+		for _, fingerprint := range s.fingerprints.WithRequest() {
+			if stopAt > 0 && result.Score >= stopAt {
+				break
+			}
+			if evidence := s.matchRequest(ctx, httpResp.URL, result, &fingerprint); evidence != nil {
+				result.Evidence = append(result.Evidence, *evidence)
+				result.Score += evidence.Weight
 			}
-			result.Evidence = append(result.Evidence, evidence)
 		}
+	}
 
-		if s.config.Verbose {
-			fmt.Fprintf(os.Stderr, "Found %d matches for %s\n", len(matches), result.Subdomain)
+	// Merge the verdict of the external matcher, unless the verdict is
+	// already settled with FirstMatch or the first match policy
+	if s.matcher != nil && (stopAt == 0 || result.Score < stopAt) {
+		if evidence := s.matchExternal(ctx, httpResp); evidence != nil {
+			result.Evidence = append(result.Evidence, *evidence)
+			result.Score += evidence.Weight
 		}
+	}
+
+	// One finding per service: the other matches of a service no longer
+	// count towards the score
+	if s.config.MatchPolicy == MatchPerService && len(result.Evidence) > 1 {
+		result.Evidence = perService(result.Evidence)
+		result.Score = 0
+		for _, evidence := range result.Evidence {
+			result.Score += evidence.Weight
+		}
+	}
+
+	// A content-rich 200 is a live site: weak evidence, such as a generic
+	// phrase or a regex, keeps its place in the result but no longer counts
+	if httpResp.LikelyLegit {
+		result.LikelyLegit = true
+		result.Score = 0
+		for i := range result.Evidence {
+			if !strongEvidence(result.Evidence[i]) {
+				result.Evidence[i].Weight = 0
+			}
+			result.Score += result.Evidence[i].Weight
+		}
+	}
+
+	result.Severity = highestSeverity(result.Evidence)
+	if len(result.Evidence) > 0 && result.Score >= s.config.Threshold {
+		result.Vulnerable = true
+		result.Status = "vulnerable"
+
+		slog.Debug("fingerprints matched", "subdomain", result.Subdomain, "matches", len(result.Evidence), "score", result.Score)
+	} else if len(result.Evidence) > 0 {
+		result.Status = "not vulnerable"
+		slog.Debug("score below threshold", "subdomain", result.Subdomain, "matches", len(result.Evidence),
+			"score", result.Score, "threshold", s.config.Threshold)
 	} else {
 		result.Status = "not vulnerable"
-		if s.config.Verbose {
-			fmt.Fprintf(os.Stderr, "No matches found for %s\n", result.Subdomain)
-		}
+		slog.Debug("no fingerprints matched", "subdomain", result.Subdomain)
 	}
 
 	return result
 }
 
-func (s *Scanner) extractSnippet(body, pattern string) string {
-	// Extract a snippet around the matched pattern
-	bodyLower := strings.ToLower(body)
-	patternLower := strings.ToLower(pattern)
-
-	index := strings.Index(bodyLower, patternLower)
-	if index == -1 {
+// serviceName returns the name of the identified service, or ""
+func serviceName(service *types.ServiceInfo) string {
+	if service == nil {
 		return ""
 	}
+	return service.Name
+}
+
+// highestSeverity returns the most severe level among the evidence, or ""
+// when there is none
+func highestSeverity(evidence []types.Evidence) string {
+	severity := ""
+	for _, e := range evidence {
+		if fingerprints.SeverityRank(e.Severity) > fingerprints.SeverityRank(severity) {
+			severity = e.Severity
+		}
+	}
+	return severity
+}
+
+// matchExternal pipes the response to the external matcher command and turns
+// a positive verdict into evidence
+func (s *Scanner) matchExternal(ctx context.Context, httpResp *types.HTTPResponse) *types.Evidence {
+	verdict, err := s.matcher.Match(ctx, &matcher.Input{
+		URL:        httpResp.URL,
+		StatusCode: httpResp.StatusCode,
+		Headers:    httpResp.Headers,
+		Body:       httpResp.Body,
+	})
+	if err != nil {
+		slog.Warn("external matcher failed", "url", httpResp.URL, "error", err)
+		return nil
+	}
+
+	if !verdict.Vulnerable {
+		return nil
+	}
+
+	weight := verdict.Weight
+	if weight == 0 {
+		weight = fingerprints.WeightString
+	}
+
+	return &types.Evidence{
+		Service:    verdict.Service,
+		Pattern:    s.config.MatcherCmd,
+		Notes:      verdict.Notes,
+		Severity:   fingerprints.SeverityMedium,
+		Weight:     weight,
+		MatchField: fingerprints.FieldExternal,
+	}
+}
+
+// matchRequest sends the request of the fingerprint to the base URL and
+// matches the fingerprint against the response. Fingerprints limited to
+// CNAME targets are only sent to subdomains pointing at their service.
+func (s *Scanner) matchRequest(ctx context.Context, baseURL string, result types.Result, fingerprint *fingerprints.Fingerprint) *types.Evidence {
+	if len(fingerprint.CNAME) > 0 && !fingerprint.MatchCNAME(result.CNAME) &&
+		!strings.EqualFold(fingerprint.Service, serviceName(result.Service)) {
+		return nil
+	}
+
+	method, path := fingerprint.Request.Target()
+	resp := s.httpClient.Send(ctx, method, baseURL+path, &httpclient.Payload{
+		ContentType: fingerprint.Request.ContentType,
+		Data:        []byte(fingerprint.Request.Body),
+	})
+	if resp.Error != nil {
+		slog.Debug("fingerprint request failed", "url", baseURL+path, "method", method, "error", resp.Error)
+		return nil
+	}
+
+	body := resp.Body
+	if IsBinary(resp.Headers.Get("Content-Type"), body) {
+		body = ""
+	}
+	match, err := fingerprint.MatchTarget(&fingerprints.Target{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		Headers:    resp.Headers,
+		CNAME:      result.CNAME,
+		Service:    serviceName(result.Service),
+	})
+	slog.Debug("fingerprint request", "url", baseURL+path, "method", method, "status_code", resp.StatusCode, "matched", match != nil)
+	if err != nil || match == nil {
+		return nil
+	}
+
+	evidence := &types.Evidence{
+		FingerprintID: fingerprint.ID,
+		Severity:      fingerprint.SeverityLevel(),
+		Service:       fingerprint.Service,
+		Pattern:       fingerprint.Describe(),
+		Notes:         fingerprint.Notes,
+		Weight:        match.Weight,
+		MatchField:    match.Field,
+		Request:       fingerprint.Request.String(),
+	}
+	if fingerprint.Pattern == "" && fingerprint.Header != nil {
+		evidence.Snippet = headerSnippet(types.Headers(resp.Headers), fingerprint.Header.Name)
+	} else if snippets := s.extractSnippets(body, fingerprint); len(snippets) > 0 {
+		evidence.Snippet = snippets[0]
+		if s.config.AllMatches {
+			evidence.Snippets = snippets
+		}
+	}
+	return evidence
+}
+
+// confirm sends the active confirmation probe to the base URL and checks the
+// response against the expectation
+func (s *Scanner) confirm(ctx context.Context, baseURL string, confirm *fingerprints.Confirm) bool {
+	method, path := confirm.Request()
+	resp := s.httpClient.Do(ctx, method, baseURL+path)
+	if resp.Error != nil {
+		slog.Debug("confirmation probe failed", "url", baseURL+path, "error", resp.Error)
+		return false
+	}
 
-	start := index - 100
-	if start < 0 {
-		start = 0
+	confirmed, err := confirm.Match(resp.StatusCode, resp.Body)
+	if err != nil {
+		slog.Warn("invalid confirmation pattern", "pattern", confirm.Pattern, "error", err)
+		return false
 	}
 
-	end := index + len(pattern) + 100
-	if end > len(body) {
-		end = len(body)
+	slog.Debug("confirmation probe", "url", baseURL+path, "status_code", resp.StatusCode, "confirmed", confirmed)
+	return confirmed
+}
+
+// DefaultSnippetWindow is the default number of body characters kept on each
+// side of a match in evidence snippets
+const DefaultSnippetWindow = 100
+
+// maxSnippets bounds the snippets recorded per evidence with AllMatches
+const maxSnippets = 20
+
+// extractSnippets returns the body around the first match of the fingerprint
+// or, with AllMatches, around every match with a distinct context
+func (s *Scanner) extractSnippets(body string, fingerprint *fingerprints.Fingerprint) []string {
+	locations := s.findMatches(body, fingerprint)
+	if locations == nil {
+		// The pattern matched the entity-decoded body
+		body = html.UnescapeString(body)
+		locations = s.findMatches(body, fingerprint)
+	}
+
+	window := s.config.SnippetWindow
+	if window <= 0 {
+		window = DefaultSnippetWindow
+	}
+
+	// Extract a snippet around each match, skipping repeats of the same text
+	var snippets []string
+	seen := make(map[string]bool)
+	for _, location := range locations {
+		start := max(location[0]-window, 0)
+		end := min(location[1]+window, len(body))
+
+		snippet := body[start:end]
+		if seen[snippet] {
+			continue
+		}
+		seen[snippet] = true
+		snippets = append(snippets, snippet)
+		if len(snippets) == maxSnippets {
+			break
+		}
+	}
+	return snippets
+}
+
+// findMatches returns the location of the first match of the fingerprint in
+// body, or of every match with AllMatches
+func (s *Scanner) findMatches(body string, fingerprint *fingerprints.Fingerprint) [][]int {
+	if s.config.AllMatches {
+		locations, _ := fingerprint.FindAll(body)
+		return locations
+	}
+	if location, _ := fingerprint.Find(body); location != nil {
+		return [][]int{location}
 	}
+	return nil
+}
 
-	return body[start:end]
+// headerSnippet formats the values of a matched header as header lines
+func headerSnippet(headers types.Headers, name string) string {
+	var lines []string
+	for _, value := range http.Header(headers).Values(name) {
+		lines = append(lines, http.CanonicalHeaderKey(name)+": "+value)
+	}
+	return strings.Join(lines, "\n")
 }
 
-// printResult prints a single scan result with colors
-func (s *Scanner) printResult(result types.Result) {
+// PrintResult prints a single scan result with colors
+func (s *Scanner) PrintResult(result types.Result) {
 	// Color coding based on vulnerability status
 	var color string
 	var status string
@@ -337,9 +1075,15 @@ func (s *Scanner) printResult(result types.Result) {
 
 	// Print status and subdomain
 	fmt.Printf("%s[%s]\033[0m %s", color, status, result.Subdomain)
+	if result.SubdomainUnicode != "" {
+		fmt.Printf(" (%s)", result.SubdomainUnicode)
+	}
 
 	// Show details only for vulnerable subdomains
 	if result.Vulnerable && len(result.Evidence) > 0 {
+		if result.Severity != "" {
+			fmt.Printf(" [%s]", result.Severity)
+		}
 		fmt.Printf(" - %s", result.Evidence[0].Service)
 
 		// Show the specific pattern that matched (truncated)
@@ -354,6 +1098,26 @@ func (s *Scanner) printResult(result types.Result) {
 		if len(result.Evidence) > 1 {
 			fmt.Printf(" (+%d more)", len(result.Evidence)-1)
 		}
+
+		if result.InferredFrom != "" {
+			fmt.Printf(" [inferred from %s]", result.InferredFrom)
+		}
+
+		if result.Service != nil && !result.Service.Claimable {
+			fmt.Printf(" [%s: not claimable]", result.Service.Name)
+		}
+	}
+
+	if result.Excluded {
+		fmt.Printf(" (excluded)")
+	}
+
+	if result.DNSAnomaly != "" {
+		fmt.Printf(" [DNS anomaly: %s]", result.DNSAnomaly)
+	}
+
+	if result.Slow {
+		fmt.Printf(" [slow]")
 	}
 
 	// Show simplified error message for errors
@@ -366,6 +1130,8 @@ func (s *Scanner) printResult(result types.Result) {
 			errorMsg = "invalid domain"
 		} else if strings.Contains(errorMsg, "timeout") {
 			errorMsg = "timeout"
+		} else if strings.Contains(errorMsg, "rate limited") {
+			errorMsg = "rate limited"
 		} else if len(errorMsg) > 30 {
 			errorMsg = errorMsg[:27] + "..."
 		}
@@ -375,9 +1141,12 @@ func (s *Scanner) printResult(result types.Result) {
 	fmt.Println()
 }
 
+// Requests returns the number of HTTP requests sent so far
+func (s *Scanner) Requests() int64 {
+	return s.httpClient.Requests()
+}
+
 // Cleanup cleans up resources
 func (s *Scanner) Cleanup() {
-	if s.rateLimiter != nil {
-		s.rateLimiter.Stop()
-	}
+	s.httpClient.Close()
 }
@@ -0,0 +1,86 @@
+package selftest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"subtake/internal/config"
+	"subtake/internal/fingerprints"
+	"subtake/internal/scanner"
+)
+
+// Result is the outcome of scanning the canonical body of one fingerprint
+type Result struct {
+	Fingerprint fingerprints.Fingerprint
+	Matches     int
+	Error       string
+}
+
+// Passed reports whether the fingerprint fired exactly once on its own body
+func (r Result) Passed() bool {
+	return r.Error == "" && r.Matches == 1
+}
+
+// Run serves the canonical body of every enabled fingerprint from a local
+// fixture server, scans each one and counts how often the fingerprint fired
+func Run(cfg *config.Config, fp *fingerprints.Fingerprints) ([]Result, error) {
+	fp = fp.Enabled()
+	results := make([]Result, len(fp.Fingerprints))
+	hosts := make([]string, len(fp.Fingerprints))
+
+	// One fixture server per fingerprint, since targets are scanned by host
+	for i, fingerprint := range fp.Fingerprints {
+		server := httptest.NewServer(fixtureHandler(fingerprint))
+		defer server.Close()
+
+		results[i].Fingerprint = fingerprint
+		hosts[i] = strings.TrimPrefix(server.URL, "http://")
+	}
+
+	s, err := scanner.New(cfg, fp)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Cleanup()
+
+	for i, scanned := range s.Scan(hosts) {
+		if scanned.Status == "error" {
+			results[i].Error = scanned.Error
+			continue
+		}
+
+		for _, evidence := range scanned.Evidence {
+			if evidence.FingerprintID == results[i].Fingerprint.ID {
+				results[i].Matches++
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// fixtureHandler serves the canonical body of the fingerprint with a status
+// code inside its bounds and the header it requires
+func fixtureHandler(fingerprint fingerprints.Fingerprint) http.Handler {
+	status := http.StatusNotFound
+	if !fingerprint.InStatusRange(status) {
+		status = fingerprint.MinStatus
+		if len(fingerprint.StatusCodes) > 0 {
+			status = fingerprint.StatusCodes[0]
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if fingerprint.Header != nil {
+			value := fingerprint.Header.Value
+			if value == "" {
+				value = "1"
+			}
+			w.Header().Set(fingerprint.Header.Name, value)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("<html><body>" + fingerprint.CanonicalBody() + "</body></html>"))
+	})
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Service describes a hosting service a subdomain can point at: the CNAME
+// suffixes that identify it and whether a dangling record can be claimed
+type Service struct {
+	Name  string   `json:"name" yaml:"name"`
+	CNAME []string `json:"cname" yaml:"cname"`
+
+	// Claimable tells whether an unclaimed resource can still be registered
+	// by anyone, i.e. whether a takeover is possible at all
+	Claimable bool `json:"claimable" yaml:"claimable"`
+
+	// Instructions explain how the resource is claimed, or why it cannot be
+	Instructions string `json:"instructions,omitempty" yaml:"instructions,omitempty"`
+}
+
+// Registry holds the known services
+type Registry struct {
+	Services []Service `json:"services" yaml:"services"`
+}
+
+// Load returns the default registry merged with the custom file, if any. A
+// custom service replaces the default one with the same name.
+func Load(customFile string) (*Registry, error) {
+	registry := Default()
+	if customFile == "" {
+		return registry, nil
+	}
+
+	custom, err := loadFromFile(customFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load custom services: %w", err)
+	}
+
+	for _, service := range custom.Services {
+		if existing := registry.Find(service.Name); existing != nil {
+			*existing = service
+			continue
+		}
+		registry.Services = append(registry.Services, service)
+	}
+	return registry, nil
+}
+
+func loadFromFile(filename string) (*Registry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var registry Registry
+
+	// Try JSON first, then YAML
+	if strings.HasSuffix(strings.ToLower(filename), ".json") {
+		err = json.Unmarshal(data, &registry)
+	} else {
+		err = yaml.Unmarshal(data, &registry)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse services file: %w", err)
+	}
+
+	for _, service := range registry.Services {
+		if service.Name == "" {
+			return nil, fmt.Errorf("service without a name in %s", filename)
+		}
+	}
+
+	return &registry, nil
+}
+
+// Find returns the service with the given name (case-insensitive), or nil
+func (r *Registry) Find(name string) *Service {
+	for i := range r.Services {
+		if strings.EqualFold(r.Services[i].Name, name) {
+			return &r.Services[i]
+		}
+	}
+	return nil
+}
+
+// Lookup identifies the service a CNAME chain points at. Hops are checked
+// from the end of the chain, so the service actually serving the subdomain
+// wins over the CDNs in front of it. It returns nil when no hop is known.
+func (r *Registry) Lookup(chain []string) *Service {
+	for i := len(chain) - 1; i >= 0; i-- {
+		for j := range r.Services {
+			if r.Services[j].Match(chain[i]) {
+				return &r.Services[j]
+			}
+		}
+	}
+	return nil
+}
+
+// Match checks if the host ends with one of the service's CNAME suffixes.
+// A suffix may use * as a wildcard within a label, e.g.
+// "s3-website-*.amazonaws.com".
+func (s *Service) Match(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, suffix := range s.CNAME {
+		suffix = strings.ToLower(strings.Trim(suffix, "."))
+		if !strings.Contains(suffix, "*") {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		// Compare the wildcard suffix with as many trailing labels of the host
+		labels := strings.Split(host, ".")
+		n := strings.Count(suffix, ".") + 1
+		if len(labels) < n {
+			continue
+		}
+		if ok, _ := path.Match(suffix, strings.Join(labels[len(labels)-n:], ".")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Default returns the built-in service registry
+func Default() *Registry {
+	return &Registry{
+		Services: []Service{
+			{
+				Name:         "GitHub Pages",
+				CNAME:        []string{"github.io"},
+				Claimable:    true,
+				Instructions: "Create a repository under the account named in the CNAME target, enable Pages and set the subdomain as its custom domain",
+			},
+			{
+				Name:         "Vercel",
+				CNAME:        []string{"vercel.app", "vercel-dns.com", "now.sh"},
+				Claimable:    true,
+				Instructions: "Add the subdomain to a Vercel project; Vercel asks for a TXT verification when the domain is linked to another account",
+			},
+			{
+				Name:         "Netlify",
+				CNAME:        []string{"netlify.app", "netlify.com"},
+				Claimable:    true,
+				Instructions: "Create a site and add the subdomain as a custom domain in its domain settings",
+			},
+			{
+				Name:         "AWS S3",
+				CNAME:        []string{"s3.amazonaws.com", "s3-*.amazonaws.com", "s3.*.amazonaws.com", "s3-website.*.amazonaws.com"},
+				Claimable:    true,
+				Instructions: "Create a bucket named after the full subdomain in the region of the CNAME target and enable static website hosting",
+			},
+			{
+				Name:         "CloudFront",
+				CNAME:        []string{"cloudfront.net"},
+				Claimable:    false,
+				Instructions: "CloudFront requires a valid certificate for alternate domain names and rejects names already in use, which prevents takeovers in practice",
+			},
+			{
+				Name:         "Fastly",
+				CNAME:        []string{"fastly.net"},
+				Claimable:    true,
+				Instructions: "Add the subdomain as a domain of a Fastly service; it is rejected when another service still owns it",
+			},
+			{
+				Name:         "Heroku",
+				CNAME:        []string{"herokuapp.com", "herokudns.com", "herokussl.com"},
+				Claimable:    true,
+				Instructions: "Create an app and add the subdomain with heroku domains:add; the DNS target changes per app, so only records pointing at herokuapp.com names are reliably claimable",
+			},
+			{
+				Name:         "GitLab Pages",
+				CNAME:        []string{"gitlab.io"},
+				Claimable:    false,
+				Instructions: "GitLab Pages requires a TXT record to verify custom domains, so a dangling record cannot be claimed without DNS access",
+			},
+			{
+				Name:         "Azure",
+				CNAME:        []string{"azurewebsites.net", "cloudapp.net", "cloudapp.azure.com", "blob.core.windows.net", "trafficmanager.net", "azureedge.net"},
+				Claimable:    true,
+				Instructions: "Create a resource of the same type whose name is the first label of the CNAME target, then bind the subdomain to it",
+			},
+			{
+				Name:         "Firebase Hosting",
+				CNAME:        []string{"firebaseapp.com", "web.app"},
+				Claimable:    false,
+				Instructions: "Firebase verifies custom domains with a TXT record, so a dangling record cannot be claimed without DNS access",
+			},
+			{
+				Name:         "Surge",
+				CNAME:        []string{"surge.sh"},
+				Claimable:    true,
+				Instructions: "Publish any project with surge using the subdomain as the domain",
+			},
+			{
+				Name:         "Shopify",
+				CNAME:        []string{"myshopify.com", "shops.myshopify.com"},
+				Claimable:    true,
+				Instructions: "Create a store and connect the subdomain as an existing domain",
+			},
+			{
+				Name:         "Pantheon",
+				CNAME:        []string{"pantheonsite.io"},
+				Claimable:    true,
+				Instructions: "Create a site and add the subdomain in the Domains settings of its live environment",
+			},
+			{
+				Name:         "Tumblr",
+				CNAME:        []string{"domains.tumblr.com"},
+				Claimable:    true,
+				Instructions: "Create a blog and set the subdomain as its custom domain",
+			},
+			{
+				Name:         "Zendesk",
+				CNAME:        []string{"zendesk.com"},
+				Claimable:    true,
+				Instructions: "Create a Zendesk account and set the subdomain as the host mapping of its help center",
+			},
+			{
+				Name:         "Help Scout",
+				CNAME:        []string{"helpscoutdocs.com"},
+				Claimable:    true,
+				Instructions: "Create a Docs site and set the subdomain as its custom domain",
+			},
+			{
+				Name:         "Desk.com",
+				CNAME:        []string{"desk.com"},
+				Claimable:    false,
+				Instructions: "Desk.com was discontinued; the dangling record should be removed",
+			},
+			{
+				Name:         "Readme.io",
+				CNAME:        []string{"readme.io", "readmessl.com"},
+				Claimable:    true,
+				Instructions: "Create a project and set the subdomain as its custom domain",
+			},
+			{
+				Name:         "Ghost",
+				CNAME:        []string{"ghost.io"},
+				Claimable:    true,
+				Instructions: "Create a Ghost(Pro) site and add the subdomain as its custom domain",
+			},
+			{
+				Name:         "Webflow",
+				CNAME:        []string{"proxy.webflow.com", "proxy-ssl.webflow.com"},
+				Claimable:    false,
+				Instructions: "Webflow verifies domain ownership before publishing to it",
+			},
+			{
+				Name:         "Unbounce",
+				CNAME:        []string{"unbouncepages.com"},
+				Claimable:    true,
+				Instructions: "Create a landing page and add the subdomain as a custom domain",
+			},
+		},
+	}
+}
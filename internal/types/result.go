@@ -1,32 +1,245 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"net/textproto"
+	"sort"
+	"time"
+)
 
 // Result represents the result of scanning a subdomain
 type Result struct {
-	Subdomain     string                 `json:"subdomain"`
-	Vulnerable    bool                   `json:"vulnerable"`
-	Status        string                 `json:"status"`
-	Evidence      []Evidence             `json:"evidence,omitempty"`
-	Error         string                 `json:"error,omitempty"`
-	HTTPResponse  *HTTPResponse          `json:"http_response,omitempty"`
-	HTTPSResponse *HTTPResponse          `json:"https_response,omitempty"`
-	ScanTime      time.Time              `json:"scan_time"`
+	Subdomain              string            `json:"subdomain"`
+	SubdomainUnicode       string            `json:"subdomain_unicode,omitempty"`
+	Tags                   map[string]string `json:"tags,omitempty"`
+	Vulnerable             bool              `json:"vulnerable"`
+	Status                 string            `json:"status"`
+	Evidence               []Evidence        `json:"evidence,omitempty"`
+	Score                  int               `json:"score"`
+	Severity               string            `json:"severity,omitempty"`
+	Error                  string            `json:"error,omitempty"`
+	ErrorType              string            `json:"error_type,omitempty"`
+	HTTPResponse           *HTTPResponse     `json:"http_response,omitempty"`
+	HTTPSResponse          *HTTPResponse     `json:"https_response,omitempty"`
+	PortResponses          []*HTTPResponse   `json:"port_responses,omitempty"`
+	CheckedURL             string            `json:"checked_url,omitempty"`
+	CNAME                  []string          `json:"cname,omitempty"`
+	Service                *ServiceInfo      `json:"service,omitempty"`
+	ServiceChain           []ServiceHop      `json:"service_chain,omitempty"`
+	DNSAnomaly             string            `json:"dns_anomaly,omitempty"`
+	DNSVerification        *DNSVerification  `json:"dns_verification,omitempty"`
+	CloudIP                *CloudIP          `json:"cloud_ip,omitempty"`
+	IP                     string            `json:"ip,omitempty"`
+	ProtocolMismatch       bool              `json:"protocol_mismatch,omitempty"`
+	Slow                   bool              `json:"slow,omitempty"`
+	LikelyLegit            bool              `json:"likely_legit,omitempty"`
+	ClusteredFalsePositive string            `json:"clustered_false_positive,omitempty"`
+	InferredFrom           string            `json:"inferred_from,omitempty"`
+	Excluded               bool              `json:"excluded,omitempty"`
+	ScanTime               time.Time         `json:"scan_time"`
 }
 
 // Evidence represents evidence of a vulnerability
 type Evidence struct {
-	Service string `json:"service"`
-	Pattern string `json:"pattern"`
-	Notes   string `json:"notes"`
-	Snippet string `json:"snippet"`
+	FingerprintID string   `json:"fingerprint_id,omitempty"`
+	Service       string   `json:"service"`
+	Severity      string   `json:"severity,omitempty"`
+	Pattern       string   `json:"pattern"`
+	Notes         string   `json:"notes"`
+	Snippet       string   `json:"snippet"`
+	Snippets      []string `json:"snippets,omitempty"` // every distinct match, with --all-matches
+	Weight        int      `json:"weight"`
+	MatchField    string   `json:"match_field"`
+	Confirmed     bool     `json:"confirmed,omitempty"`
+	Request       string   `json:"request,omitempty"` // request of the matched response when not the GET, e.g. "POST /"
 }
 
 // HTTPResponse represents an HTTP response
 type HTTPResponse struct {
-	URL        string            `json:"url"`
-	StatusCode int               `json:"status_code"`
-	Headers    map[string]string `json:"headers"`
-	Body       string            `json:"body"`
-	Error      string            `json:"error,omitempty"`
+	URL          string   `json:"url"`
+	StatusCode   int      `json:"status_code"`
+	Headers      Headers  `json:"headers"`
+	Body         string   `json:"body"`
+	Binary       bool     `json:"binary,omitempty"`       // body is a summary of binary data
+	BodySize     int      `json:"body_size,omitempty"`    // decoded size, before truncation
+	LikelyLegit  bool     `json:"likely_legit,omitempty"` // content-rich 200 of a live site
+	Error        string   `json:"error,omitempty"`
+	Raw          string   `json:"raw,omitempty"` // bytes received when the response was not valid HTTP
+	TLS          *TLSInfo `json:"tls,omitempty"`
+	Cached       bool     `json:"cached,omitempty"`
+	EmptyRetries int      `json:"empty_retries,omitempty"`  // attempts repeated because of an empty body
+	HTTPFallback bool     `json:"http1_fallback,omitempty"` // repeated over HTTP/1.1 after an HTTP/2 error
+	Timing       *Timing  `json:"timing,omitempty"`         // phases of the request, with --timing
+	FullBody     []byte   `json:"-"`                        // untruncated body, only kept when configured
+
+	// Request is the request the response answered; it is not part of the
+	// results and is nil for cached responses
+	Request *HTTPRequest `json:"-"`
+}
+
+// Timing is the duration of the phases of a request in milliseconds. Phases
+// that did not happen, e.g. the DNS lookup on a reused connection, are 0.
+type Timing struct {
+	DNS       float64 `json:"dns_ms"`
+	Connect   float64 `json:"connect_ms"`
+	TLS       float64 `json:"tls_ms"`
+	FirstByte float64 `json:"first_byte_ms"`
+}
+
+// HTTPRequest describes a request that was sent, with credentials redacted
+type HTTPRequest struct {
+	Method   string
+	Headers  Headers
+	Proto    string
+	Started  time.Time
+	Duration time.Duration
+}
+
+// Responses returns every recorded response: HTTPS, HTTP, then the responses
+// from additional ports
+func (r *Result) Responses() []*HTTPResponse {
+	var responses []*HTTPResponse
+	if r.HTTPSResponse != nil {
+		responses = append(responses, r.HTTPSResponse)
+	}
+	if r.HTTPResponse != nil {
+		responses = append(responses, r.HTTPResponse)
+	}
+	return append(responses, r.PortResponses...)
+}
+
+// CheckedResponse returns the response the verdict was based on. Results
+// without a recorded URL fall back to HTTPS when it succeeded, otherwise HTTP.
+func (r *Result) CheckedResponse() *HTTPResponse {
+	if r.CheckedURL != "" {
+		for _, resp := range r.Responses() {
+			if resp.URL == r.CheckedURL {
+				return resp
+			}
+		}
+		return nil
+	}
+
+	if r.HTTPSResponse != nil && r.HTTPSResponse.Error == "" {
+		return r.HTTPSResponse
+	}
+	if r.HTTPResponse != nil && r.HTTPResponse.Error == "" {
+		return r.HTTPResponse
+	}
+	return nil
+}
+
+// PrimaryService returns the service of the heaviest evidence, or an empty
+// string when there is none
+func (r *Result) PrimaryService() string {
+	service := ""
+	weight := -1
+	for _, evidence := range r.Evidence {
+		if evidence.Weight > weight {
+			service = evidence.Service
+			weight = evidence.Weight
+		}
+	}
+	return service
+}
+
+// ServiceInfo describes the hosting service the CNAME chain points at, as
+// identified by the service registry
+type ServiceInfo struct {
+	Name         string `json:"name"`
+	Claimable    bool   `json:"claimable"`
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// ServiceHop is a hop of the CNAME chain along with the service it belongs
+// to. Takeover marks the hop the takeover was found at; in layered setups,
+// e.g. a CDN in front of a storage bucket, it may be deep in the chain.
+type ServiceHop struct {
+	Host      string `json:"host"`
+	Service   string `json:"service,omitempty"`
+	Claimable bool   `json:"claimable,omitempty"`
+	Takeover  bool   `json:"takeover,omitempty"`
+}
+
+// DNSVerification holds the DNS answers recorded for a finding right after it
+// was found
+type DNSVerification struct {
+	Records  []DNSRecord `json:"records,omitempty"`
+	NXDOMAIN bool        `json:"nxdomain,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Time     time.Time   `json:"time"`
+}
+
+// DNSRecord is a DNS answer record
+type DNSRecord struct {
+	Name  string `json:"name"`
+	TTL   uint32 `json:"ttl"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// CloudIP is a cloud provider range an address of the subdomain lies in
+type CloudIP struct {
+	IP       string `json:"ip"`
+	Provider string `json:"provider"`
+	Range    string `json:"range"`
+}
+
+// TLSInfo represents the TLS connection details of an HTTPS response
+type TLSInfo struct {
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipher_suite,omitempty"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    []string  `json:"dns_names,omitempty"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+// Headers holds response headers with canonical names and all their values
+type Headers map[string][]string
+
+// Get returns the first value of the header, matching the name
+// case-insensitively
+func (h Headers) Get(name string) string {
+	values := h[textproto.CanonicalMIMEHeaderKey(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Names returns the header names in sorted order, for output that has to be
+// stable across runs
+func (h Headers) Names() []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnmarshalJSON accepts both lists of values and the single string values
+// written by older versions
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	headers := make(Headers, len(raw))
+	for name, value := range raw {
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			var single string
+			if err := json.Unmarshal(value, &single); err != nil {
+				return err
+			}
+			values = []string{single}
+		}
+		headers[textproto.CanonicalMIMEHeaderKey(name)] = values
+	}
+
+	*h = headers
+	return nil
 }
@@ -6,7 +6,14 @@ import (
 	"subtake/cmd"
 )
 
+// Build information, set via -ldflags
+var (
+	version = "dev"
+	commit  = "none"
+)
+
 func main() {
+	cmd.SetVersionInfo(version, commit)
 	if err := cmd.Execute(); err != nil {
 		os.Exit(1)
 	}